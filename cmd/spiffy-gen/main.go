@@ -0,0 +1,54 @@
+// Command spiffy-gen emits Go types for existing tables, ex:
+//
+//	DB_NAME=app spiffy-gen -schema public -package model -populate -out model/tables.go
+//
+// It connects with the `DATABASE_URL` or `DB_*` environment variables, like `spiffy.NewConfigFromEnv`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/blendlabs/spiffy"
+	"github.com/blendlabs/spiffy/generator"
+)
+
+func main() {
+	schema := flag.String("schema", "", "the schema to read tables from; defaults to the current schema")
+	packageName := flag.String("package", generator.DefaultPackageName, "the package name of the generated file")
+	tables := flag.String("tables", "", "a comma separated list of tables to generate types for; defaults to every table in the schema")
+	populate := flag.Bool("populate", false, "if `Populate` methods should be generated")
+	output := flag.String("out", "", "the file to write; defaults to stdout")
+	flag.Parse()
+
+	conn, err := spiffy.NewFromConfig(spiffy.NewConfigFromEnv()).Open()
+	if err != nil {
+		fatal(err)
+	}
+	defer conn.Close()
+
+	gen := generator.New(conn).WithSchema(*schema).WithPackageName(*packageName).WithPopulate(*populate)
+	if len(*tables) > 0 {
+		gen = gen.WithTables(strings.Split(*tables, ",")...)
+	}
+
+	source, err := gen.Generate()
+	if err != nil {
+		fatal(err)
+	}
+	if len(*output) == 0 {
+		os.Stdout.Write(source)
+		return
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "spiffy-gen: %+v\n", err)
+	os.Exit(1)
+}
@@ -13,6 +13,7 @@ import (
 // --------------------------------------------------------------------------------
 
 // NewColumnFromFieldTag reads the contents of a field tag, ex: `json:"foo" db:"bar,isprimarykey,isserial"
+// The `db_type`, `db_default` and `db_index` tags set the column's data type, default expression and index for generated DDL.
 func NewColumnFromFieldTag(field reflect.StructField) *Column {
 	db := field.Tag.Get("db")
 	if db != "-" {
@@ -20,6 +21,9 @@ func NewColumnFromFieldTag(field reflect.StructField) *Column {
 		col.FieldName = field.Name
 		col.ColumnName = strings.ToLower(field.Name)
 		col.FieldType = field.Type
+		col.DataType = field.Tag.Get("db_type")
+		col.DefaultValue = field.Tag.Get("db_default")
+		col.IndexName, col.IsIndexed = field.Tag.Lookup("db_index")
 		if db != "" {
 			pieces := strings.Split(db, ",")
 
@@ -34,6 +38,8 @@ func NewColumnFromFieldTag(field reflect.StructField) *Column {
 				col.IsNullable = strings.Contains(strings.ToLower(args), "nullable")
 				col.IsReadOnly = strings.Contains(strings.ToLower(args), "readonly")
 				col.IsJSON = strings.Contains(strings.ToLower(args), "json")
+				col.IsUnique = strings.Contains(strings.ToLower(args), "unique")
+				col.IsDefault = strings.Contains(strings.ToLower(args), "default")
 			}
 		}
 		return &col
@@ -54,6 +60,17 @@ type Column struct {
 	IsNullable   bool
 	IsReadOnly   bool
 	IsJSON       bool
+	IsUnique     bool
+	IsDefault    bool
+
+	// DataType is the postgres data type of the column for generated DDL; it is inferred from the field type if empty.
+	DataType string
+	// DefaultValue is the default expression of the column for generated DDL, ex: `now()`.
+	DefaultValue string
+	// IsIndexed is if the column is indexed in generated DDL.
+	IsIndexed bool
+	// IndexName is the name of the column's index; columns with the same index name share a multi-column index.
+	IndexName string
 }
 
 // SetValue sets the field on a database mapped object to the instance of `value`.
@@ -114,6 +131,11 @@ func (c Column) SetValue(object interface{}, value interface{}) error {
 	return nil
 }
 
+// IsZero returns if the column's field on a given database mapped object is the zero value for its type.
+func (c Column) IsZero(object interface{}) bool {
+	return reflectValue(object).FieldByName(c.FieldName).IsZero()
+}
+
 // GetValue returns the value for a column on a given database mapped object.
 func (c Column) GetValue(object DatabaseMapped) interface{} {
 	value := reflectValue(object)
@@ -133,6 +133,9 @@ type ColumnCollection struct {
 	notReadOnly    *ColumnCollection
 	primaryKeys    *ColumnCollection
 	notPrimaryKeys *ColumnCollection
+	uniqueKeys     *ColumnCollection
+	returning      *ColumnCollection
+	defaults       *ColumnCollection
 	writeColumns   *ColumnCollection
 	updateColumns  *ColumnCollection
 }
@@ -231,6 +234,82 @@ func (cc *ColumnCollection) NotPrimaryKeys() *ColumnCollection {
 	return cc.notPrimaryKeys
 }
 
+// UniqueKeys are columns tagged `unique`, used as an alternate conflict target for upserts.
+func (cc *ColumnCollection) UniqueKeys() *ColumnCollection {
+	if cc.uniqueKeys != nil {
+		return cc.uniqueKeys
+	}
+
+	newCC := newColumnCollectionWithPrefix(cc.columnPrefix)
+
+	for _, c := range cc.columns {
+		if c.IsUnique {
+			newCC.Add(c)
+		}
+	}
+
+	cc.uniqueKeys = newCC
+	return cc.uniqueKeys
+}
+
+// Returning are serial and `default` columns, which are read back from the database after they're written.
+func (cc *ColumnCollection) Returning() *ColumnCollection {
+	if cc.returning != nil {
+		return cc.returning
+	}
+
+	newCC := newColumnCollectionWithPrefix(cc.columnPrefix)
+
+	for _, c := range cc.columns {
+		if c.IsSerial || c.IsDefault {
+			newCC.Add(c)
+		}
+	}
+
+	cc.returning = newCC
+	return cc.returning
+}
+
+// Defaults are columns tagged `default`, whose values can be supplied by the database.
+func (cc *ColumnCollection) Defaults() *ColumnCollection {
+	if cc.defaults != nil {
+		return cc.defaults
+	}
+
+	newCC := newColumnCollectionWithPrefix(cc.columnPrefix)
+
+	for _, c := range cc.columns {
+		if c.IsDefault {
+			newCC.Add(c)
+		}
+	}
+
+	cc.defaults = newCC
+	return cc.defaults
+}
+
+// NotZeroDefaults returns the columns less any `default` columns that are zero on a given instance,
+// along with the names of the columns that were left out so the database can supply their values.
+func (cc *ColumnCollection) NotZeroDefaults(instance interface{}) (*ColumnCollection, []string) {
+	var omitted []string
+	for _, c := range cc.columns {
+		if c.IsDefault && c.IsZero(instance) {
+			omitted = append(omitted, c.ColumnName)
+		}
+	}
+	if len(omitted) == 0 {
+		return cc, nil
+	}
+
+	newCC := newColumnCollectionWithPrefix(cc.columnPrefix)
+	for _, c := range cc.columns {
+		if !(c.IsDefault && c.IsZero(instance)) {
+			newCC.Add(c)
+		}
+	}
+	return newCC, omitted
+}
+
 // Serials are columns we have to return the id of.
 func (cc *ColumnCollection) Serials() *ColumnCollection {
 	if cc.serials != nil {
@@ -2,6 +2,7 @@ package spiffy
 
 import (
 	"testing"
+	"time"
 
 	"github.com/blendlabs/go-assert"
 )
@@ -119,3 +120,41 @@ func TestColumnCollectionWriteColumns(t *testing.T) {
 	writeCols := meta.WriteColumns()
 	assert.NotZero(writeCols.Len())
 }
+
+type defaultsObj struct {
+	ID         int        `db:"id,pk,serial"`
+	Name       string     `db:"name"`
+	CreatedUTC time.Time  `db:"created_utc,default"`
+	Version    int        `db:"version,default"`
+	UpdatedUTC *time.Time `db:"updated_utc"`
+}
+
+func (do defaultsObj) TableName() string {
+	return "defaults_object"
+}
+
+func TestColumnCollectionReturning(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := getCachedColumnCollectionFromInstance(defaultsObj{})
+	assert.Equal("id,created_utc,version", meta.Returning().ColumnNamesCSV())
+	assert.Equal("created_utc,version", meta.Defaults().ColumnNamesCSV())
+}
+
+func TestColumnCollectionNotZeroDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := getCachedColumnCollectionFromInstance(defaultsObj{}).NotReadOnly().NotSerials()
+
+	cols, omitted := meta.NotZeroDefaults(defaultsObj{Name: "foo"})
+	assert.Equal("name,updated_utc", cols.ColumnNamesCSV())
+	assert.Equal([]string{"created_utc", "version"}, omitted)
+
+	cols, omitted = meta.NotZeroDefaults(defaultsObj{Name: "foo", Version: 2})
+	assert.Equal("name,version,updated_utc", cols.ColumnNamesCSV())
+	assert.Equal([]string{"created_utc"}, omitted)
+
+	cols, omitted = meta.NotZeroDefaults(defaultsObj{Name: "foo", CreatedUTC: time.Now().UTC(), Version: 2})
+	assert.Equal(meta, cols)
+	assert.Empty(omitted)
+}
@@ -0,0 +1,120 @@
+package spiffy
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// CommentTagService is the comment tag for the service name.
+	CommentTagService = "service"
+	// CommentTagLabel is the comment tag for the statement label.
+	CommentTagLabel = "label"
+	// CommentTagRoute is the comment tag for the route that issued the statement.
+	CommentTagRoute = "route"
+	// CommentTagTraceParent is the comment tag for the w3c trace context, ex: `00-<trace id>-<span id>-01`.
+	CommentTagTraceParent = "traceparent"
+)
+
+type commentContextKey string
+
+// ContextTagger returns comment tags pulled from a statement's context.
+type ContextTagger func(ctx context.Context) map[string]string
+
+// WithCommentRoute returns a context that tags statements with the route that issued them.
+func WithCommentRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, commentContextKey(CommentTagRoute), route)
+}
+
+// WithCommentTraceParent returns a context that tags statements with a w3c trace context.
+func WithCommentTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, commentContextKey(CommentTagTraceParent), traceParent)
+}
+
+// DefaultContextTagger is the `ContextTagger` that pulls the route and trace parent set with
+// `WithCommentRoute` and `WithCommentTraceParent`.
+func DefaultContextTagger(ctx context.Context) map[string]string {
+	tags := map[string]string{}
+	if route, ok := ctx.Value(commentContextKey(CommentTagRoute)).(string); ok && len(route) > 0 {
+		tags[CommentTagRoute] = route
+	}
+	if traceParent, ok := ctx.Value(commentContextKey(CommentTagTraceParent)).(string); ok && len(traceParent) > 0 {
+		tags[CommentTagTraceParent] = traceParent
+	}
+	return tags
+}
+
+// WithStatementComments enables appending a sqlcommenter-style comment with the service name, statement label and the
+// context's tags to the statements built by invocations and queries, and returns a reference to the connection.
+// Statements from the statement cache are prepared once per label, so they're only tagged with the service and label.
+func (dbc *Connection) WithStatementComments(service string) *Connection {
+	dbc.commentsEnabled = true
+	dbc.commentService = service
+	return dbc
+}
+
+// WithContextTagger sets the function that pulls comment tags from a statement's context, and returns a reference to the connection.
+// It defaults to `DefaultContextTagger`.
+func (dbc *Connection) WithContextTagger(tagger ContextTagger) *Connection {
+	dbc.contextTagger = tagger
+	return dbc
+}
+
+// commentStatement appends a comment with the statement's tags, if statement comments are enabled.
+func (dbc *Connection) commentStatement(statement, label string, ctx context.Context, cached bool) string {
+	if !dbc.commentsEnabled {
+		return statement
+	}
+
+	tags := map[string]string{}
+	if !cached && ctx != nil {
+		tagger := dbc.contextTagger
+		if tagger == nil {
+			tagger = DefaultContextTagger
+		}
+		for key, value := range tagger(ctx) {
+			tags[key] = value
+		}
+	}
+	if len(dbc.commentService) > 0 {
+		tags[CommentTagService] = dbc.commentService
+	}
+	if len(label) > 0 {
+		tags[CommentTagLabel] = label
+	}
+	return AppendComment(statement, tags)
+}
+
+// AppendComment appends a sqlcommenter-style comment with a set of tags to a statement, ex: `/*label='users_get',service='api'*/`.
+// Tags are sorted by key and their keys and values are url encoded, so the comment is stable for the same tags.
+// Statements that already have a comment are returned as they are.
+func AppendComment(statement string, tags map[string]string) string {
+	if len(tags) == 0 || strings.Contains(statement, "/*") || strings.Contains(statement, "--") {
+		return statement
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, commentEscape(key)+"='"+commentEscape(tags[key])+"'")
+	}
+	comment := "/*" + strings.Join(pairs, ",") + "*/"
+
+	trimmed := strings.TrimRight(statement, " \t\r\n")
+	if strings.HasSuffix(trimmed, ";") {
+		return strings.TrimSuffix(trimmed, ";") + " " + comment + ";"
+	}
+	return trimmed + " " + comment
+}
+
+// commentEscape url encodes a comment tag key or value.
+func commentEscape(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
@@ -0,0 +1,43 @@
+package spiffy
+
+import (
+	"context"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAppendComment(t *testing.T) {
+	assert := assert.New(t)
+
+	tags := map[string]string{
+		CommentTagService:     "api",
+		CommentTagRoute:       "/users/{id}",
+		CommentTagLabel:       "users_get",
+		CommentTagTraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	expected := "SELECT 1 /*label='users_get',route='%2Fusers%2F%7Bid%7D',service='api',traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/"
+	assert.Equal(expected, AppendComment("SELECT 1", tags))
+	assert.Equal(expected+";", AppendComment("SELECT 1;\n", tags))
+
+	assert.Equal("SELECT 1", AppendComment("SELECT 1", nil))
+	assert.Equal("SELECT 1 /* existing */", AppendComment("SELECT 1 /* existing */", tags))
+	assert.Equal("SELECT 1 /*label='it%27s%20quoted'*/", AppendComment("SELECT 1", map[string]string{CommentTagLabel: "it's quoted"}))
+}
+
+func TestConnectionCommentStatement(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := New()
+	assert.Equal("SELECT 1", conn.commentStatement("SELECT 1", "test", context.Background(), false))
+
+	conn.WithStatementComments("api")
+	ctx := WithCommentTraceParent(WithCommentRoute(context.Background(), "/users"), "00-abc-def-01")
+	assert.Equal("SELECT 1 /*label='test',route='%2Fusers',service='api',traceparent='00-abc-def-01'*/", conn.commentStatement("SELECT 1", "test", ctx, false))
+	assert.Equal("SELECT 1 /*label='test',service='api'*/", conn.commentStatement("SELECT 1", "test", ctx, true))
+
+	conn.WithContextTagger(func(ctx context.Context) map[string]string {
+		return map[string]string{"tenant": "acme"}
+	})
+	assert.Equal("SELECT 1 /*service='api',tenant='acme'*/", conn.commentStatement("SELECT 1", "", ctx, false))
+}
@@ -1,12 +1,20 @@
 package spiffy
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	exception "github.com/blendlabs/go-exception"
 	util "github.com/blendlabs/go-util"
 	"github.com/blendlabs/go-util/env"
+	"github.com/lib/pq"
+	yaml "gopkg.in/yaml.v2"
 )
 
 const (
@@ -54,6 +62,8 @@ const (
 	DefaultMaxLifetime time.Duration = 0
 	// DefaultBufferPoolSize is the default number of buffer pool entries to maintain.
 	DefaultBufferPoolSize = 1024
+	// DefaultStatementCacheSize is the default maximum number of prepared statements to cache.
+	DefaultStatementCacheSize = 1024
 )
 
 // NewConfig creates a new config.
@@ -71,12 +81,109 @@ func NewConfig() *Config {
 //	-	DB_USER 		= Username
 //	-	DB_PASSWORD 	= Password
 //	-	DB_SSLMODE 		= SSLMode
+//	-	DB_STATEMENT_TIMEOUT 	= StatementTimeout
+//	-	DB_LOCK_TIMEOUT 	= LockTimeout
+//	-	DB_APPLICATION_NAME 	= ApplicationName
+//	-	DB_TIMEZONE 	= Timezone
+//
+// If `DATABASE_URL` is set, the host, port, database, username, password and ssl mode are parsed from it.
 func NewConfigFromEnv() *Config {
 	var config Config
 	env.Env().ReadInto(&config)
+	// an invalid dsn is left as is, and fails at `Open`.
+	config.populateFromDSN()
 	return &config
 }
 
+// NewConfigFromFile returns a new config read from a yaml or json file, by its extension, with the environment
+// variables that `NewConfigFromEnv` reads overlaid on top, and the fields parsed from the DSN, if any, on top of those.
+// The config is validated, and every problem with it is returned together in a `*ConfigValidationError`.
+func NewConfigFromFile(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+
+	var config Config
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(contents, &config)
+	} else {
+		err = yaml.Unmarshal(contents, &config)
+	}
+	if err != nil {
+		return nil, exception.Newf("cannot read config file `%s`: %v", path, err)
+	}
+
+	err = env.Env().ReadInto(&config)
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	if err = config.populateFromDSN(); err != nil {
+		return nil, err
+	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ConfigValidationError is returned by `Validate` with every problem with a config.
+type ConfigValidationError struct {
+	Problems []string
+}
+
+// Error implements error.
+func (cve *ConfigValidationError) Error() string {
+	return fmt.Sprintf("invalid config: %s", strings.Join(cve.Problems, "; "))
+}
+
+// Validate returns a `*ConfigValidationError` if any of the config's values are invalid, so they're caught before `Open`.
+func (c Config) Validate() error {
+	var problems []string
+	if len(c.DSN) > 0 {
+		if _, err := ParseDSN(c.DSN); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(c.SSLMode) > 0 {
+		switch c.SSLMode {
+		case SSLModeDisable, SSLModeAllow, SSLModePrefer, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+		default:
+			problems = append(problems, fmt.Sprintf("sslMode `%s` is not a known ssl mode", c.SSLMode))
+		}
+	}
+	if len(c.Port) > 0 {
+		if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("port `%s` is not a port number", c.Port))
+		}
+	}
+	if c.IdleConnections < 0 {
+		problems = append(problems, fmt.Sprintf("idleConnections `%d` is negative", c.IdleConnections))
+	}
+	if c.MaxConnections < 0 {
+		problems = append(problems, fmt.Sprintf("maxConnections `%d` is negative", c.MaxConnections))
+	}
+	if c.IdleConnections > 0 && c.IdleConnections > c.GetMaxConnections() {
+		problems = append(problems, fmt.Sprintf("idleConnections `%d` is more than maxConnections `%d`", c.IdleConnections, c.GetMaxConnections()))
+	}
+	if c.BufferPoolSize < 0 {
+		problems = append(problems, fmt.Sprintf("bufferPoolSize `%d` is negative", c.BufferPoolSize))
+	}
+	if c.MaxLifetime < 0 {
+		problems = append(problems, fmt.Sprintf("maxLifetime `%v` is negative", c.MaxLifetime))
+	}
+	if c.StatementTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("statementTimeout `%v` is negative", c.StatementTimeout))
+	}
+	if c.LockTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("lockTimeout `%v` is negative", c.LockTimeout))
+	}
+	if len(problems) > 0 {
+		return &ConfigValidationError{Problems: problems}
+	}
+	return nil
+}
+
 // Config is a set of connection config options.
 type Config struct {
 	// DSN is a fully formed DSN (this skips DSN formation from all other variables outside `schema`).
@@ -105,6 +212,22 @@ type Config struct {
 	MaxLifetime time.Duration `json:"maxLifetime" yaml:"maxLifetime" env:"DB_MAX_LIFETIME"`
 	// BufferPoolSize is the number of query composition buffers to maintain.
 	BufferPoolSize int `json:"bufferPoolSize" yaml:"bufferPoolSize" env:"DB_BUFFER_POOL_SIZE"`
+	// StatementCacheSize is the maximum number of prepared statements to cache, evicting the least recently used.
+	// Set it to a negative number for an unbounded cache.
+	StatementCacheSize int `json:"statementCacheSize" yaml:"statementCacheSize" env:"DB_STATEMENT_CACHE_SIZE"`
+
+	// StatementTimeout is the maximum time a statement can run before the server cancels it.
+	StatementTimeout time.Duration `json:"statementTimeout" yaml:"statementTimeout" env:"DB_STATEMENT_TIMEOUT"`
+	// LockTimeout is the maximum time a statement can wait to acquire a lock before the server cancels it.
+	LockTimeout time.Duration `json:"lockTimeout" yaml:"lockTimeout" env:"DB_LOCK_TIMEOUT"`
+	// ApplicationName is the name reported by the server for connections, ex. in `pg_stat_activity`.
+	ApplicationName string `json:"applicationName" yaml:"applicationName" env:"DB_APPLICATION_NAME"`
+	// Timezone is the session time zone for connections, ex. `UTC`.
+	Timezone string `json:"timezone" yaml:"timezone" env:"DB_TIMEZONE"`
+	// InitStatements are run, in order, on every new connection after the settings above are applied.
+	InitStatements []string `json:"initStatements" yaml:"initStatements"`
+	// Params are extra connection string parameters, ex: `connect_timeout`; they're also read from the DSN.
+	Params map[string]string `json:"params" yaml:"params"`
 }
 
 // WithDSN sets the config dsn and returns a reference to the config.
@@ -155,6 +278,36 @@ func (c *Config) WithSSLMode(sslMode string) *Config {
 	return c
 }
 
+// WithStatementTimeout sets the config statement timeout and returns a reference to the config.
+func (c *Config) WithStatementTimeout(timeout time.Duration) *Config {
+	c.StatementTimeout = timeout
+	return c
+}
+
+// WithLockTimeout sets the config lock timeout and returns a reference to the config.
+func (c *Config) WithLockTimeout(timeout time.Duration) *Config {
+	c.LockTimeout = timeout
+	return c
+}
+
+// WithApplicationName sets the config application name and returns a reference to the config.
+func (c *Config) WithApplicationName(applicationName string) *Config {
+	c.ApplicationName = applicationName
+	return c
+}
+
+// WithTimezone sets the config timezone and returns a reference to the config.
+func (c *Config) WithTimezone(timezone string) *Config {
+	c.Timezone = timezone
+	return c
+}
+
+// WithInitStatements adds statements to run on every new connection and returns a reference to the config.
+func (c *Config) WithInitStatements(statements ...string) *Config {
+	c.InitStatements = append(c.InitStatements, statements...)
+	return c
+}
+
 // GetDSN returns the postgres dsn (fully quallified url) for the config.
 // If unset, it's generated from the host, port and database.
 func (c Config) GetDSN(inherited ...string) string {
@@ -221,15 +374,69 @@ func (c Config) GetBufferPoolSize(inherited ...int) int {
 	return util.Coalesce.Int(c.BufferPoolSize, DefaultBufferPoolSize, inherited...)
 }
 
+// GetStatementCacheSize returns the maximum number of prepared statements to cache or a default.
+func (c Config) GetStatementCacheSize(inherited ...int) int {
+	return util.Coalesce.Int(c.StatementCacheSize, DefaultStatementCacheSize, inherited...)
+}
+
+// GetStatementTimeout returns the statement timeout or a default.
+func (c Config) GetStatementTimeout(inherited ...time.Duration) time.Duration {
+	return util.Coalesce.Duration(c.StatementTimeout, 0, inherited...)
+}
+
+// GetLockTimeout returns the lock timeout or a default.
+func (c Config) GetLockTimeout(inherited ...time.Duration) time.Duration {
+	return util.Coalesce.Duration(c.LockTimeout, 0, inherited...)
+}
+
+// GetApplicationName returns the application name or a default.
+func (c Config) GetApplicationName(inherited ...string) string {
+	return util.Coalesce.String(c.ApplicationName, "", inherited...)
+}
+
+// GetTimezone returns the session timezone or a default.
+func (c Config) GetTimezone(inherited ...string) string {
+	return util.Coalesce.String(c.Timezone, "", inherited...)
+}
+
+// CreateInitStatements returns the statements to run on every new connection.
+// They set the search path, timeouts, application name and timezone (if configured), followed by `InitStatements`.
+func (c Config) CreateInitStatements() []string {
+	var statements []string
+	if len(c.GetSchema()) > 0 {
+		statements = append(statements, fmt.Sprintf("SET search_path TO %s,public", c.GetSchema()))
+	}
+	if c.GetStatementTimeout() > 0 {
+		statements = append(statements, fmt.Sprintf("SET statement_timeout TO %d", c.GetStatementTimeout()/time.Millisecond))
+	}
+	if c.GetLockTimeout() > 0 {
+		statements = append(statements, fmt.Sprintf("SET lock_timeout TO %d", c.GetLockTimeout()/time.Millisecond))
+	}
+	if len(c.GetApplicationName()) > 0 {
+		statements = append(statements, fmt.Sprintf("SET application_name TO %s", pq.QuoteLiteral(c.GetApplicationName())))
+	}
+	if len(c.GetTimezone()) > 0 {
+		statements = append(statements, fmt.Sprintf("SET TIME ZONE %s", pq.QuoteLiteral(c.GetTimezone())))
+	}
+	return append(statements, c.InitStatements...)
+}
+
 // CreateDSN creates a postgres connection string from the config.
 func (c Config) CreateDSN() string {
 	if len(c.GetDSN()) != 0 {
 		return c.GetDSN()
 	}
 
-	var sslMode string
+	query := url.Values{}
 	if len(c.GetSSLMode()) > 0 {
-		sslMode = fmt.Sprintf("?sslmode=%s", url.QueryEscape(c.GetSSLMode()))
+		query.Set("sslmode", c.GetSSLMode())
+	}
+	for key, value := range c.Params {
+		query.Set(key, value)
+	}
+	var sslMode string
+	if len(query) > 0 {
+		sslMode = "?" + query.Encode()
 	}
 
 	var port string
@@ -1,7 +1,11 @@
 package spiffy
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	assert "github.com/blendlabs/go-assert"
 )
@@ -87,3 +91,94 @@ func TestConfigCreateDSN(t *testing.T) {
 	cfg = &Config{}
 	assert.Equal("postgres://localhost:5432/postgres?sslmode=disable", cfg.CreateDSN())
 }
+
+func TestConfigCreateInitStatements(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := &Config{}
+	assert.Empty(cfg.CreateInitStatements())
+
+	cfg = &Config{
+		Schema:           "mortgages",
+		StatementTimeout: 5 * time.Second,
+		LockTimeout:      500 * time.Millisecond,
+		ApplicationName:  "bailey's app",
+		Timezone:         "UTC",
+		InitStatements:   []string{"SET work_mem TO '64MB'"},
+	}
+
+	assert.Equal([]string{
+		"SET search_path TO mortgages,public",
+		"SET statement_timeout TO 5000",
+		"SET lock_timeout TO 500",
+		"SET application_name TO 'bailey''s app'",
+		"SET TIME ZONE 'UTC'",
+		"SET work_mem TO '64MB'",
+	}, cfg.CreateInitStatements())
+}
+
+func TestNewConfigFromFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "spiffy_config")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	yamlPath := filepath.Join(dir, "config.yml")
+	assert.Nil(ioutil.WriteFile(yamlPath, []byte("database: blend\nsslMode: require\nmaxConnections: 8\nstatementTimeout: 30s\n"), 0644))
+	cfg, err := NewConfigFromFile(yamlPath)
+	assert.Nil(err)
+	assert.Equal("blend", cfg.Database)
+	assert.Equal(SSLModeRequire, cfg.SSLMode)
+	assert.Equal(8, cfg.MaxConnections)
+	assert.Equal(30*time.Second, cfg.StatementTimeout)
+
+	jsonPath := filepath.Join(dir, "config.json")
+	assert.Nil(ioutil.WriteFile(jsonPath, []byte(`{"database": "blend", "port": "5433", "idleConnections": 4}`), 0644))
+	cfg, err = NewConfigFromFile(jsonPath)
+	assert.Nil(err)
+	assert.Equal("5433", cfg.Port)
+	assert.Equal(4, cfg.IdleConnections)
+
+	invalidPath := filepath.Join(dir, "invalid.yml")
+	assert.Nil(ioutil.WriteFile(invalidPath, []byte("port: postgres\nsslMode: always\n"), 0644))
+	_, err = NewConfigFromFile(invalidPath)
+	assert.NotNil(err)
+	assert.Len(err.(*ConfigValidationError).Problems, 2)
+
+	// the dsn's fields are validated.
+	dsnPath := filepath.Join(dir, "dsn.yml")
+	assert.Nil(ioutil.WriteFile(dsnPath, []byte("dsn: postgres://bailey@db.internal:70000/blend\n"), 0644))
+	_, err = NewConfigFromFile(dsnPath)
+	assert.NotNil(err)
+	assert.Equal([]string{"port `70000` is not a port number"}, err.(*ConfigValidationError).Problems)
+
+	_, err = NewConfigFromFile(filepath.Join(dir, "missing.yml"))
+	assert.NotNil(err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(Config{}.Validate())
+	assert.Nil(Config{Port: "5432", SSLMode: SSLModeVerifyFull, IdleConnections: 8, MaxConnections: 8}.Validate())
+	// an unset maximum is the default, which the idle connections are checked against.
+	assert.Nil(Config{IdleConnections: DefaultMaxConnections}.Validate())
+
+	err := Config{
+		Port:            "70000",
+		SSLMode:         "on",
+		IdleConnections: 16,
+		MaxConnections:  8,
+		BufferPoolSize:  -1,
+		LockTimeout:     -time.Second,
+	}.Validate()
+	assert.NotNil(err)
+	assert.Equal([]string{
+		"sslMode `on` is not a known ssl mode",
+		"port `70000` is not a port number",
+		"idleConnections `16` is more than maxConnections `8`",
+		"bufferPoolSize `-1` is negative",
+		"lockTimeout `-1s` is negative",
+	}, err.(*ConfigValidationError).Problems)
+}
@@ -0,0 +1,151 @@
+package spiffy
+
+import (
+	"bytes"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+// UpsertResult is the outcome of an upsert.
+type UpsertResult string
+
+const (
+	// UpsertResultInserted means the row did not exist and was inserted.
+	UpsertResultInserted UpsertResult = "inserted"
+	// UpsertResultUpdated means the row conflicted and was updated.
+	UpsertResultUpdated UpsertResult = "updated"
+	// UpsertResultSkipped means the row conflicted and was left as is, either because of `DoNothing()`
+	// or because the update predicate did not match.
+	UpsertResultSkipped UpsertResult = "skipped"
+)
+
+// OnConflict returns a new conflict that targets the given columns.
+// If no columns are given, the primary key columns of the object are used.
+func OnConflict(columnNames ...string) *Conflict {
+	return &Conflict{columnNames: columnNames}
+}
+
+// OnConflictUnique returns a new conflict that targets the column tagged `unique` on the object.
+// Each `unique` column is its own constraint, so the object must have exactly one; use `OnConflict` with
+// the column names, or `OnConstraint`, for objects with more than one.
+func OnConflictUnique() *Conflict {
+	return &Conflict{useUniqueKeys: true}
+}
+
+// OnConstraint returns a new conflict that targets a named unique or exclusion constraint.
+func OnConstraint(constraintName string) *Conflict {
+	return &Conflict{constraintName: constraintName}
+}
+
+// Conflict describes the `ON CONFLICT` target and action of an upsert.
+// By default it updates every writable column that is not a primary key.
+type Conflict struct {
+	constraintName string
+	columnNames    []string
+	useUniqueKeys  bool
+
+	doNothing     bool
+	updateColumns []string
+	where         string
+}
+
+// DoNothing leaves conflicting rows as they are.
+func (c *Conflict) DoNothing() *Conflict {
+	c.doNothing = true
+	return c
+}
+
+// DoUpdate updates only the given columns on conflicting rows.
+// If no columns are given, every writable column that is not a primary key is updated.
+func (c *Conflict) DoUpdate(columnNames ...string) *Conflict {
+	c.doNothing = false
+	c.updateColumns = columnNames
+	return c
+}
+
+// Where adds a predicate to the update, ex: `excluded.updated_utc > my_table.updated_utc`.
+// Conflicting rows that do not match the predicate are left as they are.
+func (c *Conflict) Where(predicate string) *Conflict {
+	c.where = predicate
+	return c
+}
+
+// targetColumnNames returns the conflict target columns for a column collection.
+func (c *Conflict) targetColumnNames(cols *ColumnCollection) ([]string, error) {
+	if len(c.columnNames) > 0 {
+		return c.columnNames, nil
+	}
+	if c.useUniqueKeys {
+		uniqueKeys := cols.UniqueKeys()
+		if uniqueKeys.Len() == 0 {
+			return nil, exception.New("no unique columns on object to upsert by.")
+		}
+		if uniqueKeys.Len() > 1 {
+			return nil, exception.Newf("more than one unique column on object to upsert by (%s); use `OnConflict` or `OnConstraint` to pick the target.", CSV(uniqueKeys.ColumnNames()))
+		}
+		return uniqueKeys.ColumnNames(), nil
+	}
+	return cols.PrimaryKeys().ColumnNames(), nil
+}
+
+// updateColumnNames returns the columns to update for a column collection.
+// By default these are the writable columns that are not primary keys and have a parameter token,
+// which leaves out zero valued `default` columns.
+func (c *Conflict) updateColumnNames(cols *ColumnCollection, tokens map[string]string) []string {
+	if len(c.updateColumns) > 0 {
+		return c.updateColumns
+	}
+	var names []string
+	for _, name := range cols.NotReadOnly().NotSerials().NotPrimaryKeys().ColumnNames() {
+		if _, hasToken := tokens[name]; hasToken {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// writeClause writes the `ON CONFLICT` clause to a buffer; `tokens` maps writable column names to their parameter tokens.
+// If there is no conflict target the clause is omitted and the upsert is a plain insert.
+func (c *Conflict) writeClause(buffer *bytes.Buffer, cols *ColumnCollection, tokens map[string]string) error {
+	targetColumns, err := c.targetColumnNames(cols)
+	if err != nil {
+		return err
+	}
+
+	if len(c.constraintName) > 0 {
+		buffer.WriteString(" ON CONFLICT ON CONSTRAINT ")
+		buffer.WriteString(c.constraintName)
+	} else if len(targetColumns) > 0 {
+		buffer.WriteString(" ON CONFLICT (")
+		buffer.WriteString(CSV(targetColumns))
+		buffer.WriteString(")")
+	} else if c.doNothing {
+		buffer.WriteString(" ON CONFLICT")
+	} else {
+		return nil
+	}
+
+	updateColumns := c.updateColumnNames(cols, tokens)
+	if c.doNothing || len(updateColumns) == 0 {
+		buffer.WriteString(" DO NOTHING")
+		return nil
+	}
+
+	buffer.WriteString(" DO UPDATE SET ")
+	for index, name := range updateColumns {
+		token, hasToken := tokens[name]
+		if !hasToken {
+			return exception.Newf("cannot update column `%s` on conflict; it is not a writable column.", name)
+		}
+		buffer.WriteString(name + " = " + token)
+		if index < (len(updateColumns) - 1) {
+			buffer.WriteRune(runeComma)
+		}
+	}
+
+	if len(c.where) > 0 {
+		buffer.WriteString(" WHERE ")
+		buffer.WriteString(c.where)
+	}
+	return nil
+}
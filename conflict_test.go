@@ -0,0 +1,95 @@
+package spiffy
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type conflictObj struct {
+	ID       int    `db:"id,pk,serial"`
+	Email    string `db:"email,unique"`
+	Name     string `db:"name"`
+	Category string `db:"category"`
+}
+
+func (co conflictObj) TableName() string {
+	return "conflict_object"
+}
+
+type conflictMultipleUniqueObj struct {
+	ID       int    `db:"id,pk,serial"`
+	Email    string `db:"email,unique"`
+	Username string `db:"username,unique"`
+}
+
+func (cmuo conflictMultipleUniqueObj) TableName() string {
+	return "conflict_multiple_unique_object"
+}
+
+func conflictTokens() map[string]string {
+	return map[string]string{"email": "$1", "name": "$2", "category": "$3"}
+}
+
+func TestConflictWriteClauseDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(upsertObj{})
+	buffer := bytes.NewBuffer(nil)
+	err := OnConflict().writeClause(buffer, cols, map[string]string{"uuid": "$1", "timestamp_utc": "$2", "category": "$3"})
+	assert.Nil(err)
+	assert.Equal(" ON CONFLICT (uuid) DO UPDATE SET timestamp_utc = $2,category = $3", buffer.String())
+}
+
+func TestConflictWriteClauseUnique(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(conflictObj{})
+	buffer := bytes.NewBuffer(nil)
+	err := OnConflictUnique().DoUpdate("name").Where("conflict_object.category = excluded.category").writeClause(buffer, cols, conflictTokens())
+	assert.Nil(err)
+	assert.Equal(" ON CONFLICT (email) DO UPDATE SET name = $2 WHERE conflict_object.category = excluded.category", buffer.String())
+}
+
+func TestConflictWriteClauseConstraintDoNothing(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(conflictObj{})
+	buffer := bytes.NewBuffer(nil)
+	err := OnConstraint("uk_conflict_object_email").DoNothing().writeClause(buffer, cols, conflictTokens())
+	assert.Nil(err)
+	assert.Equal(" ON CONFLICT ON CONSTRAINT uk_conflict_object_email DO NOTHING", buffer.String())
+}
+
+func TestConflictWriteClauseInvalidUpdateColumn(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(conflictObj{})
+	buffer := bytes.NewBuffer(nil)
+	err := OnConflict("email").DoUpdate("id").writeClause(buffer, cols, conflictTokens())
+	assert.NotNil(err)
+}
+
+func TestConflictWriteClauseNoUniqueColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(upsertObj{})
+	buffer := bytes.NewBuffer(nil)
+	err := OnConflictUnique().writeClause(buffer, cols, map[string]string{})
+	assert.NotNil(err)
+}
+
+func TestConflictWriteClauseMultipleUniqueColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(conflictMultipleUniqueObj{})
+	buffer := bytes.NewBuffer(nil)
+	err := OnConflictUnique().writeClause(buffer, cols, map[string]string{"email": "$1", "username": "$2"})
+	assert.NotNil(err)
+	assert.Empty(buffer.String())
+
+	err = OnConflict("username").DoUpdate("email").writeClause(buffer, cols, map[string]string{"email": "$1", "username": "$2"})
+	assert.Nil(err)
+	assert.Equal(" ON CONFLICT (username) DO UPDATE SET email = $1", buffer.String())
+}
@@ -4,6 +4,7 @@
 package spiffy
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
@@ -40,6 +41,7 @@ func New() *Connection {
 		useStatementCache:  DefaultUseStatementCache,
 		statementCacheLock: &sync.Mutex{},
 		connectionLock:     &sync.Mutex{},
+		txs:                newTxTracker(),
 	}
 }
 
@@ -51,6 +53,7 @@ func NewFromConfig(cfg *Config) *Connection {
 		useStatementCache:  cfg.GetUseStatementCache(), //doesnt actually help perf, maybe someday.
 		statementCacheLock: &sync.Mutex{},
 		connectionLock:     &sync.Mutex{},
+		txs:                newTxTracker(),
 	}
 }
 
@@ -71,8 +74,24 @@ type Connection struct {
 	bufferPool *BufferPool
 	log        *logger.Logger
 
+	eventArgs        ArgRedactor
+	eventContextKeys []interface{}
+	txs              *txTracker
+	tracer           Tracer
+	metrics          MetricsCollector
+
+	slowQueryThreshold   time.Duration
+	slowQueryExplainRate float64
+	slowQueryExplains    int32
+
+	commentsEnabled bool
+	commentService  string
+	contextTagger   ContextTagger
+
 	useStatementCache bool
 	statementCache    *StatementCache
+
+	strict bool
 }
 
 // Close implements a closer.
@@ -97,18 +116,99 @@ func (dbc *Connection) Logger() *logger.Logger {
 	return dbc.log
 }
 
-func (dbc *Connection) fireEvent(flag logger.Flag, query string, elapsed time.Duration, err error, optionalQueryLabel ...string) {
+// WithEventArgs sets how statement arguments are written to events, and returns a reference to the connection.
+// Arguments are left out of events unless a redactor is set; use `IncludeArgs` to write them as they are.
+func (dbc *Connection) WithEventArgs(redactor ArgRedactor) *Connection {
+	dbc.eventArgs = redactor
+	return dbc
+}
+
+// WithEventContextKeys sets the context keys whose values are written to events, and returns a reference to the connection.
+func (dbc *Connection) WithEventContextKeys(keys ...interface{}) *Connection {
+	dbc.eventContextKeys = keys
+	return dbc
+}
+
+// WithTracer sets the tracer that starts spans around database calls, and returns a reference to the connection.
+func (dbc *Connection) WithTracer(tracer Tracer) *Connection {
+	dbc.tracer = tracer
+	return dbc
+}
+
+// Tracer returns the connection's tracer, which is a `NoopTracer` unless one is set.
+func (dbc *Connection) Tracer() Tracer {
+	if dbc.tracer == nil {
+		return NoopTracer{}
+	}
+	return dbc.tracer
+}
+
+// WithMetrics sets the collector that observes every statement the connection runs, and returns a reference to the connection.
+// Use `NewMetrics` and `NewMetricsHandler` to expose the metrics in the prometheus text format.
+func (dbc *Connection) WithMetrics(collector MetricsCollector) *Connection {
+	dbc.metrics = collector
+	return dbc
+}
+
+// Metrics returns the connection's metrics collector, if one is set.
+func (dbc *Connection) Metrics() MetricsCollector {
+	return dbc.metrics
+}
+
+// observeStatement passes a statement to the metrics collector, if one is set.
+// Statements labeled with their own text, like those run with `Exec` and `ExecInTx`, are passed without a label,
+// so every distinct statement doesn't become its own series.
+func (dbc *Connection) observeStatement(label, statement string, elapsed time.Duration, err error) {
+	if dbc.metrics != nil {
+		if label == statement {
+			label = ""
+		}
+		dbc.metrics.ObserveStatement(label, elapsed, err)
+	}
+}
+
+// fireEvent triggers the event and statement events for a statement with the details collected as it ran.
+func (dbc *Connection) fireEvent(flag logger.Flag, query, queryLabel string, elapsed time.Duration, err error, details eventDetails, ctx context.Context, tx *sql.Tx) {
 	if dbc.log != nil {
-		var queryLabel string
-		if len(optionalQueryLabel) > 0 {
-			queryLabel = optionalQueryLabel[0]
+		details.args = dbc.redactArgs(queryLabel, details.args)
+		details.contextValues = dbc.contextValues(ctx)
+		if tx != nil {
+			details.txID = TxID(tx)
 		}
 
-		dbc.log.Trigger(NewEvent(flag, queryLabel, elapsed, err))
-		dbc.log.Trigger(NewStatementEvent(flag, queryLabel, query, elapsed, err))
+		event := NewEvent(flag, queryLabel, elapsed, err)
+		event.eventDetails = details
+		dbc.log.Trigger(event)
+		dbc.log.Trigger(StatementEvent{Event: event, queryBody: query})
 	}
 }
 
+// redactArgs returns the statement arguments to write to events.
+func (dbc *Connection) redactArgs(queryLabel string, args []interface{}) []interface{} {
+	if dbc.eventArgs == nil || len(args) == 0 {
+		return nil
+	}
+	redacted := make([]interface{}, len(args))
+	for index, arg := range args {
+		redacted[index] = dbc.eventArgs(queryLabel, index, arg)
+	}
+	return redacted
+}
+
+// contextValues returns the values of the event context keys that are set on a context.
+func (dbc *Connection) contextValues(ctx context.Context) map[string]interface{} {
+	if ctx == nil || len(dbc.eventContextKeys) == 0 {
+		return nil
+	}
+	values := map[string]interface{}{}
+	for _, key := range dbc.eventContextKeys {
+		if value := ctx.Value(key); value != nil {
+			values[fmt.Sprint(key)] = value
+		}
+	}
+	return values
+}
+
 // EnableStatementCache opts to cache statements for the connection.
 func (dbc *Connection) EnableStatementCache() {
 	dbc.useStatementCache = true
@@ -125,17 +225,31 @@ func (dbc *Connection) WithUseStatementCache(enabled bool) *Connection {
 	return dbc
 }
 
+// WithStrict sets if invocations should default to strict mode, and returns the connection.
+// In strict mode updates and deletes that match no rows return a `NotFoundError`.
+func (dbc *Connection) WithStrict(enabled bool) *Connection {
+	dbc.strict = enabled
+	return dbc
+}
+
+// Strict returns if invocations default to strict mode.
+func (dbc *Connection) Strict() bool {
+	return dbc.strict
+}
+
 // StatementCache returns the statement cache.
 func (dbc *Connection) StatementCache() *StatementCache {
 	return dbc.statementCache
 }
 
 // openNewSQLConnection returns a new connection object.
+// The connection pool is opened with a connector that applies the config's init statements to every new connection.
 func (dbc *Connection) openNewSQLConnection() (*sql.DB, error) {
-	dbConn, err := sql.Open("postgres", dbc.Config.CreateDSN())
+	connector, err := NewConnector(dbc.Config)
 	if err != nil {
 		return nil, exception.Wrap(err)
 	}
+	dbConn := sql.OpenDB(connector)
 
 	if dbc.Config != nil {
 		dbConn.SetConnMaxLifetime(dbc.Config.GetMaxLifetime())
@@ -143,15 +257,9 @@ func (dbc *Connection) openNewSQLConnection() (*sql.DB, error) {
 		dbConn.SetMaxOpenConns(dbc.Config.GetMaxConnections())
 	}
 
-	if len(dbc.Config.GetSchema()) > 0 {
-		_, err = dbConn.Exec(fmt.Sprintf("SET search_path TO %s,public;", dbc.Config.GetSchema()))
-		if err != nil {
-			return nil, exception.Wrap(err)
-		}
-	}
-
 	_, err = dbConn.Exec("select 'ok!'")
 	if err != nil {
+		dbConn.Close()
 		return nil, exception.Wrap(err)
 	}
 
@@ -176,20 +284,121 @@ func (dbc *Connection) Open() (*Connection, error) {
 }
 
 // Begin starts a new transaction.
+// If the connection has a tracer, or its logger has any of the `db.tx` flags enabled, the transaction is tracked until
+// it's committed or rolled back, which finishes its span and triggers the `db.tx.commit` or `db.tx.rollback` event.
+// That is the case whether it's ended with `Commit` or `Rollback` on the connection, or directly on the `*sql.Tx`.
+// The exception is a connection whose `Connection` pool was set by hand rather than opened with a `Connector`:
+// there, only transactions ended through the connection trigger events, and the spans of the others are finished,
+// tagged with `TxResultUnknown`, once they are garbage collected.
 func (dbc *Connection) Begin() (*sql.Tx, error) {
-	if dbc.Connection != nil {
-		tx, txErr := dbc.Connection.Begin()
-		return tx, exception.Wrap(txErr)
-	}
+	return dbc.BeginContext(context.Background())
+}
 
+// BeginContext starts a new transaction with a context; see `Begin` for how transactions are tracked.
+// The transaction's span is a child of any span in the context, and the spans of statements run in the transaction
+// are children of the transaction's span. As with `sql.DB.BeginTx`, the transaction is rolled back if the context is canceled.
+func (dbc *Connection) BeginContext(ctx context.Context) (*sql.Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	connection, err := dbc.Open()
 	if err != nil {
 		return nil, exception.Wrap(err)
 	}
-	tx, err := connection.Begin()
+	if !dbc.tracksTxs() {
+		tx, err := connection.Connection.BeginTx(ctx, nil)
+		return tx, exception.Wrap(err)
+	}
+
+	hookCtx, hook := withTxHook(ctx)
+	tx, err := connection.Connection.BeginTx(hookCtx, nil)
+	dbc.txBegan(ctx, tx, hook, err)
 	return tx, exception.Wrap(err)
 }
 
+// Commit commits a transaction, triggering a `db.tx.commit` event if the transaction is tracked; see `Begin`.
+// It returns the error from the transaction as is.
+func (dbc *Connection) Commit(tx *sql.Tx) error {
+	if tx == nil {
+		return nil
+	}
+	err := tx.Commit()
+	dbc.txEnded(FlagTxCommit, tx, err)
+	return err
+}
+
+// Rollback rolls a transaction back, triggering a `db.tx.rollback` event if the transaction is tracked; see `Begin`.
+// It returns the error from the transaction as is; rolling back a transaction that was already committed
+// returns `sql.ErrTxDone` without an event, so it is safe to defer.
+func (dbc *Connection) Rollback(tx *sql.Tx) error {
+	if tx == nil {
+		return nil
+	}
+	err := tx.Rollback()
+	dbc.txEnded(FlagTxRollback, tx, err)
+	return err
+}
+
+// tracksTxs returns if transactions should be tracked for spans and lifecycle events.
+func (dbc *Connection) tracksTxs() bool {
+	if dbc.tracer != nil {
+		return true
+	}
+	return dbc.log != nil && (dbc.log.IsEnabled(FlagTxBegin) || dbc.log.IsEnabled(FlagTxCommit) || dbc.log.IsEnabled(FlagTxRollback))
+}
+
+// txBegan starts tracking a transaction and its span, and triggers a `db.tx.begin` event.
+// The hook reports the transaction's end if the pool was opened with a `Connector`.
+func (dbc *Connection) txBegan(ctx context.Context, tx *sql.Tx, hook *txHook, err error) {
+	if err == nil {
+		span, spanCtx := dbc.Tracer().StartSpan(ctx, SpanTx)
+		dbc.txs.begin(tx, span, spanCtx)
+	}
+	if dbc.log != nil {
+		dbc.log.Trigger(NewTxEvent(FlagTxBegin, TxID(tx), 0, 0, err))
+	}
+	if err == nil {
+		// the observer holds the id rather than the transaction, which would otherwise keep itself alive through its context.
+		id := TxID(tx)
+		hook.observe(func(flag logger.Flag, err error) {
+			if state, tracked := dbc.txs.remove(id); tracked {
+				dbc.txFinished(flag, id, state, err)
+			}
+		})
+	}
+}
+
+// txSpanContext returns the context that carries a tracked transaction's span, or nil if the transaction isn't tracked.
+func (dbc *Connection) txSpanContext(tx *sql.Tx) context.Context {
+	if tx == nil {
+		return nil
+	}
+	return dbc.txs.spanContext(tx)
+}
+
+// txStatement counts a statement executed in a tracked transaction.
+func (dbc *Connection) txStatement(tx *sql.Tx) {
+	if tx != nil {
+		dbc.txs.statement(tx)
+	}
+}
+
+// txEnded stops tracking a transaction, finishes its span and triggers a commit or rollback event, if it was tracked.
+func (dbc *Connection) txEnded(flag logger.Flag, tx *sql.Tx, err error) {
+	if state, tracked := dbc.txs.end(tx); tracked {
+		dbc.txFinished(flag, TxID(tx), state, err)
+	}
+}
+
+// txFinished finishes an ended transaction's span and triggers its commit or rollback event.
+func (dbc *Connection) txFinished(flag logger.Flag, id string, state *txState, err error) {
+	state.span.SetTag(TagTxResult, string(flag))
+	finishSpan(state.span, err)
+	if dbc.log != nil {
+		dbc.log.Trigger(NewTxEvent(flag, id, time.Since(state.started), state.statements, err))
+	}
+}
+
 // Prepare prepares a new statement for the connection.
 func (dbc *Connection) Prepare(statement string, tx *sql.Tx) (*sql.Stmt, error) {
 	if tx != nil {
@@ -222,26 +431,33 @@ func (dbc *Connection) ensureStatementCache() error {
 			if err != nil {
 				return exception.Wrap(err)
 			}
-			dbc.statementCache = newStatementCache(db.Connection)
+			dbc.statementCache = newStatementCache(db.Connection).WithMaxSize(dbc.Config.GetStatementCacheSize())
 		}
 	}
 	return nil
 }
 
 // PrepareCached prepares a potentially cached statement.
+// Within a transaction a cached statement is bound to the transaction with `tx.Stmt`, which is closed when the transaction ends.
+// If the statement is not cached yet it is prepared on the transaction, and the cache is populated in the background
+// so the transaction never waits on another pooled connection; see `StatementCache` for how background prepares are limited.
 func (dbc *Connection) PrepareCached(id, statement string, tx *sql.Tx) (*sql.Stmt, error) {
-	if tx != nil {
-		stmt, err := tx.Prepare(statement)
-		if err != nil {
-			return nil, exception.Wrap(err)
-		}
-		return stmt, nil
+	if !dbc.useStatementCache {
+		return dbc.Prepare(statement, tx)
+	}
+
+	if err := dbc.ensureStatementCache(); err != nil {
+		return nil, err
 	}
 
-	if dbc.useStatementCache {
-		dbc.ensureStatementCache()
+	if tx == nil {
 		return dbc.statementCache.Prepare(id, statement)
 	}
+
+	if cached := dbc.statementCache.get(id); cached != nil {
+		return tx.Stmt(cached), nil
+	}
+	dbc.statementCache.prepareInBackground(id, statement)
 	return dbc.Prepare(statement, tx)
 }
 
@@ -255,6 +471,7 @@ func (dbc *Connection) DB(txs ...*sql.Tx) *DB {
 		conn:       dbc,
 		tx:         OptionalTx(txs...),
 		fireEvents: dbc.log != nil,
+		strict:     dbc.strict,
 	}
 }
 
@@ -264,6 +481,7 @@ func (dbc *Connection) Invoke(txs ...*sql.Tx) *Invocation {
 		conn:       dbc,
 		tx:         OptionalTx(txs...),
 		fireEvents: dbc.log != nil,
+		strict:     dbc.strict,
 	}
 }
 
@@ -296,6 +514,16 @@ func (dbc *Connection) ExecInTxWithCacheLabel(statement, cacheLabel string, tx *
 	return dbc.Invoke(tx).WithLabel(cacheLabel).Exec(statement, args...)
 }
 
+// ExecWithResult runs the statement and returns the result.
+func (dbc *Connection) ExecWithResult(statement string, args ...interface{}) (sql.Result, error) {
+	return dbc.ExecInTxWithResult(statement, nil, args...)
+}
+
+// ExecInTxWithResult runs a statement within a transaction and returns the result.
+func (dbc *Connection) ExecInTxWithResult(statement string, tx *sql.Tx, args ...interface{}) (sql.Result, error) {
+	return dbc.Invoke(tx).WithLabel(statement).ExecWithResult(statement, args...)
+}
+
 // Query runs the selected statement and returns a Query.
 func (dbc *Connection) Query(statement string, args ...interface{}) *Query {
 	return dbc.QueryInTx(statement, nil, args...)
@@ -356,6 +584,26 @@ func (dbc *Connection) CreateManyInTx(objects interface{}, tx *sql.Tx) (err erro
 	return dbc.Invoke(tx).CreateMany(objects)
 }
 
+// CopyIn bulk loads a slice of objects into their table with `COPY FROM STDIN`.
+func (dbc *Connection) CopyIn(collection interface{}) error {
+	return dbc.CopyInInTx(collection, nil)
+}
+
+// CopyInInTx bulk loads a slice of objects into their table with `COPY FROM STDIN` within a transaction.
+func (dbc *Connection) CopyInInTx(collection interface{}, tx *sql.Tx) error {
+	return dbc.Invoke(tx).CopyIn(collection)
+}
+
+// CopyInFrom streams objects from an iterator into their table with `COPY FROM STDIN`.
+func (dbc *Connection) CopyInFrom(ctx context.Context, iterator CopyInIterator) error {
+	return dbc.CopyInFromInTx(ctx, iterator, nil)
+}
+
+// CopyInFromInTx streams objects from an iterator into their table with `COPY FROM STDIN` within a transaction.
+func (dbc *Connection) CopyInFromInTx(ctx context.Context, iterator CopyInIterator, tx *sql.Tx) error {
+	return dbc.Invoke(tx).WithCtx(ctx).CopyInFrom(ctx, iterator)
+}
+
 // Update updates an object.
 func (dbc *Connection) Update(object DatabaseMapped) error {
 	return dbc.UpdateInTx(object, nil)
@@ -366,6 +614,16 @@ func (dbc *Connection) UpdateInTx(object DatabaseMapped, tx *sql.Tx) (err error)
 	return dbc.Invoke(tx).Update(object)
 }
 
+// UpdateWithResult updates an object and returns the result.
+func (dbc *Connection) UpdateWithResult(object DatabaseMapped) (sql.Result, error) {
+	return dbc.UpdateWithResultInTx(object, nil)
+}
+
+// UpdateWithResultInTx updates an object wrapped in a transaction and returns the result.
+func (dbc *Connection) UpdateWithResultInTx(object DatabaseMapped, tx *sql.Tx) (sql.Result, error) {
+	return dbc.Invoke(tx).UpdateWithResult(object)
+}
+
 // Exists returns a bool if a given object exists (utilizing the primary key columns if they exist).
 func (dbc *Connection) Exists(object DatabaseMapped) (bool, error) {
 	return dbc.ExistsInTx(object, nil)
@@ -386,6 +644,16 @@ func (dbc *Connection) DeleteInTx(object DatabaseMapped, tx *sql.Tx) (err error)
 	return dbc.Invoke(tx).Delete(object)
 }
 
+// DeleteWithResult deletes an object from the database and returns the result.
+func (dbc *Connection) DeleteWithResult(object DatabaseMapped) (sql.Result, error) {
+	return dbc.DeleteWithResultInTx(object, nil)
+}
+
+// DeleteWithResultInTx deletes an object from the database wrapped in a transaction and returns the result.
+func (dbc *Connection) DeleteWithResultInTx(object DatabaseMapped, tx *sql.Tx) (sql.Result, error) {
+	return dbc.Invoke(tx).DeleteWithResult(object)
+}
+
 // Upsert inserts the object if it doesn't exist already (as defined by its primary keys) or updates it.
 func (dbc *Connection) Upsert(object DatabaseMapped) error {
 	return dbc.UpsertInTx(object, nil)
@@ -396,6 +664,36 @@ func (dbc *Connection) UpsertInTx(object DatabaseMapped, tx *sql.Tx) (err error)
 	return dbc.Invoke(tx).Upsert(object)
 }
 
+// UpsertWith inserts the object or resolves a conflict as described by `conflict`.
+func (dbc *Connection) UpsertWith(object DatabaseMapped, conflict *Conflict) (UpsertResult, error) {
+	return dbc.UpsertWithInTx(object, conflict, nil)
+}
+
+// UpsertWithInTx inserts the object or resolves a conflict as described by `conflict` wrapped in a transaction.
+func (dbc *Connection) UpsertWithInTx(object DatabaseMapped, conflict *Conflict, tx *sql.Tx) (UpsertResult, error) {
+	return dbc.Invoke(tx).UpsertWith(object, conflict)
+}
+
+// UpsertMany inserts or updates many objects in batches, returning the number of rows affected.
+func (dbc *Connection) UpsertMany(objects interface{}) (int64, error) {
+	return dbc.UpsertManyInTx(objects, nil)
+}
+
+// UpsertManyInTx inserts or updates many objects in batches wrapped in a transaction, returning the number of rows affected.
+func (dbc *Connection) UpsertManyInTx(objects interface{}, tx *sql.Tx) (int64, error) {
+	return dbc.Invoke(tx).UpsertMany(objects)
+}
+
+// DeleteMany deletes many objects in batches, returning the number of rows affected.
+func (dbc *Connection) DeleteMany(objects interface{}) (int64, error) {
+	return dbc.DeleteManyInTx(objects, nil)
+}
+
+// DeleteManyInTx deletes many objects in batches wrapped in a transaction, returning the number of rows affected.
+func (dbc *Connection) DeleteManyInTx(objects interface{}, tx *sql.Tx) (int64, error) {
+	return dbc.Invoke(tx).DeleteMany(objects)
+}
+
 // Truncate fully removes an tables rows in a single opertation.
 func (dbc *Connection) Truncate(object DatabaseMapped) error {
 	return dbc.TruncateInTx(object, nil)
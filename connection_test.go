@@ -1,6 +1,7 @@
 package spiffy
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"testing"
@@ -110,6 +111,39 @@ func TestConnectionStatementCacheQuery(t *testing.T) {
 	a.True(conn.StatementCache().HasStatement("status"))
 }
 
+func TestConnectionStatementCacheInTx(t *testing.T) {
+	a := assert.New(t)
+
+	conn := NewFromEnv()
+	defer func() {
+		closeErr := conn.Close()
+		a.Nil(closeErr)
+	}()
+
+	conn.EnableStatementCache()
+	_, err := conn.Open()
+	a.Nil(err)
+
+	// populate the cache outside a transaction.
+	var ok string
+	err = conn.Query("select 'ok!'").CachedAs("status").Scan(&ok)
+	a.Nil(err)
+	a.True(conn.StatementCache().HasStatement("status"))
+	hits := conn.StatementCache().Stats().Hits
+
+	tx, err := conn.Begin()
+	a.Nil(err)
+	defer tx.Rollback()
+
+	for x := 0; x < 3; x++ {
+		err = conn.QueryInTx("select 'ok!'", tx).CachedAs("status").Scan(&ok)
+		a.Nil(err)
+		a.Equal("ok!", ok)
+	}
+	a.Equal(hits+3, conn.StatementCache().Stats().Hits)
+	a.Equal(1, conn.StatementCache().Len())
+}
+
 func TestCRUDMethods(t *testing.T) {
 	a := assert.New(t)
 	tx, err := Default().Begin()
@@ -493,3 +527,325 @@ func TestConnectionInvalidatesBadCachedStatements(t *testing.T) {
 	_, err = conn.Query(queryStatement).Any()
 	assert.Nil(err)
 }
+
+func TestConnectionRetriesStaleCachedStatements(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := NewFromEnv()
+	defer conn.Close()
+
+	conn.EnableStatementCache()
+	_, err := conn.Open()
+	assert.Nil(err)
+
+	err = conn.Exec(`CREATE TABLE stale_plan (id int not null, name varchar(64))`)
+	assert.Nil(err)
+	defer func() {
+		err = conn.Exec(`DROP TABLE stale_plan`)
+		assert.Nil(err)
+	}()
+
+	err = conn.Exec(`INSERT INTO stale_plan (id, name) VALUES ($1, $2)`, 1, "Foo")
+	assert.Nil(err)
+
+	var name string
+	err = conn.Query(`SELECT * FROM stale_plan`).CachedAs("stale_plan_all").Each(func(r *sql.Rows) error {
+		var id int
+		return r.Scan(&id, &name)
+	})
+	assert.Nil(err)
+
+	err = conn.Exec(`ALTER TABLE stale_plan ALTER COLUMN id TYPE bigint`)
+	assert.Nil(err)
+
+	// the cached plan is stale, but the first call after the change should still succeed.
+	err = conn.Query(`SELECT * FROM stale_plan`).CachedAs("stale_plan_all").Each(func(r *sql.Rows) error {
+		var id int64
+		return r.Scan(&id, &name)
+	})
+	assert.Nil(err)
+	assert.Equal("Foo", name)
+}
+
+func TestConnectionCopyIn(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = createTable(tx)
+	assert.Nil(err)
+
+	var objects []benchObj
+	for x := 0; x < 100; x++ {
+		objects = append(objects, benchObj{
+			Name:      fmt.Sprintf("test_object_%d", x),
+			Timestamp: time.Now().UTC(),
+			Amount:    1005.0,
+			Pending:   true,
+			Category:  fmt.Sprintf("category_%d", x),
+		})
+	}
+
+	err = Default().CopyInInTx(objects, tx)
+	assert.Nil(err)
+
+	var count int
+	err = Default().QueryInTx(`select count(*) from bench_object`, tx).Scan(&count)
+	assert.Nil(err)
+	assert.Equal(100, count)
+}
+
+func TestConnectionCopyInFrom(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = createTable(tx)
+	assert.Nil(err)
+
+	var index int
+	err = Default().CopyInFromInTx(context.Background(), func() (DatabaseMapped, error) {
+		if index >= 50 {
+			return nil, nil
+		}
+		index = index + 1
+		return &benchObj{
+			Name:      fmt.Sprintf("test_object_%d", index),
+			Timestamp: time.Now().UTC(),
+			Amount:    1005.0,
+			Category:  fmt.Sprintf("category_%d", index),
+		}, nil
+	}, tx)
+	assert.Nil(err)
+
+	var count int
+	err = Default().QueryInTx(`select count(*) from bench_object`, tx).Scan(&count)
+	assert.Nil(err)
+	assert.Equal(50, count)
+}
+
+func TestConnectionUpsertMany(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = createUpserObjectTable(tx)
+	assert.Nil(err)
+
+	var objects []upsertObj
+	for x := 0; x < 10; x++ {
+		objects = append(objects, upsertObj{
+			UUID:      UUIDv4().ToShortString(),
+			Timestamp: time.Now().UTC(),
+			Category:  fmt.Sprintf("category_%d", x),
+		})
+	}
+
+	affected, err := Default().UpsertManyInTx(objects, tx)
+	assert.Nil(err)
+	assert.Equal(int64(10), affected)
+
+	for x := range objects {
+		objects[x].Category = "test"
+	}
+
+	affected, err = Default().UpsertManyInTx(objects, tx)
+	assert.Nil(err)
+	assert.Equal(int64(10), affected)
+
+	var verify upsertObj
+	err = Default().GetInTx(&verify, tx, objects[0].UUID)
+	assert.Nil(err)
+	assert.Equal("test", verify.Category)
+}
+
+func TestConnectionDeleteMany(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = createUpserObjectTable(tx)
+	assert.Nil(err)
+
+	var objects []upsertObj
+	for x := 0; x < 10; x++ {
+		objects = append(objects, upsertObj{
+			UUID:      UUIDv4().ToShortString(),
+			Timestamp: time.Now().UTC(),
+			Category:  fmt.Sprintf("category_%d", x),
+		})
+	}
+
+	err = Default().CreateManyInTx(objects, tx)
+	assert.Nil(err)
+
+	affected, err := Default().DeleteManyInTx(objects[:5], tx)
+	assert.Nil(err)
+	assert.Equal(int64(5), affected)
+
+	var count int
+	err = Default().QueryInTx(`select count(*) from upsert_object`, tx).Scan(&count)
+	assert.Nil(err)
+	assert.Equal(5, count)
+}
+
+func TestConnectionUpsertWith(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = Default().ExecInTx(`CREATE TABLE IF NOT EXISTS conflict_object (id serial primary key, email varchar(255) unique, name varchar(255), category varchar(255));`, tx)
+	assert.Nil(err)
+
+	obj := &conflictObj{Email: "foo@bar.com", Name: "foo", Category: "bar"}
+	result, err := Default().UpsertWithInTx(obj, OnConflictUnique(), tx)
+	assert.Nil(err)
+	assert.Equal(UpsertResultInserted, result)
+	assert.NotZero(obj.ID)
+
+	obj.Name = "not foo"
+	result, err = Default().UpsertWithInTx(obj, OnConflictUnique().DoUpdate("name"), tx)
+	assert.Nil(err)
+	assert.Equal(UpsertResultUpdated, result)
+
+	result, err = Default().UpsertWithInTx(obj, OnConflictUnique().DoNothing(), tx)
+	assert.Nil(err)
+	assert.Equal(UpsertResultSkipped, result)
+
+	var verify conflictObj
+	err = Default().GetInTx(&verify, tx, obj.ID)
+	assert.Nil(err)
+	assert.Equal("not foo", verify.Name)
+}
+
+func TestConnectionCreateReturnsDefaults(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = Default().ExecInTx(`CREATE TABLE IF NOT EXISTS defaults_object (id serial primary key, name varchar(255), created_utc timestamp not null default current_timestamp, version int not null default 1, updated_utc timestamp);`, tx)
+	assert.Nil(err)
+
+	obj := &defaultsObj{Name: "foo"}
+	err = Default().CreateInTx(obj, tx)
+	assert.Nil(err)
+	assert.NotZero(obj.ID)
+	assert.False(obj.CreatedUTC.IsZero())
+	assert.Equal(1, obj.Version)
+
+	// zero valued `default` columns are written on update, so they can be reset.
+	createdUTC := obj.CreatedUTC
+	obj.Name = "bar"
+	obj.Version = 0
+	err = Default().UpdateInTx(obj, tx)
+	assert.Nil(err)
+	assert.Equal(createdUTC, obj.CreatedUTC)
+	assert.Equal(0, obj.Version)
+
+	upserted := &defaultsObj{Name: "baz", Version: 5}
+	err = Default().UpsertInTx(upserted, tx)
+	assert.Nil(err)
+	assert.NotZero(upserted.ID)
+	assert.False(upserted.CreatedUTC.IsZero())
+	assert.Equal(5, upserted.Version)
+}
+
+func TestConnectionWithResult(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = seedObjects(10, tx)
+	assert.Nil(err)
+
+	res, err := Default().ExecInTxWithResult(`update bench_object set pending = true`, tx)
+	assert.Nil(err)
+	rowsAffected, err := res.RowsAffected()
+	assert.Nil(err)
+	assert.Equal(int64(10), rowsAffected)
+
+	var obj benchObj
+	err = Default().QueryInTx(`select * from bench_object limit 1`, tx).Out(&obj)
+	assert.Nil(err)
+
+	res, err = Default().DeleteWithResultInTx(&obj, tx)
+	assert.Nil(err)
+	rowsAffected, err = res.RowsAffected()
+	assert.Nil(err)
+	assert.Equal(int64(1), rowsAffected)
+
+	res, err = Default().UpdateWithResultInTx(&obj, tx)
+	assert.Nil(err)
+	rowsAffected, err = res.RowsAffected()
+	assert.Nil(err)
+	assert.Zero(rowsAffected)
+}
+
+func TestConnectionStrict(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = createTable(tx)
+	assert.Nil(err)
+
+	obj := &benchObj{ID: 12345, Name: "does_not_exist"}
+
+	err = Default().Invoke(tx).Update(obj)
+	assert.Nil(err)
+
+	err = Default().Invoke(tx).WithStrict(true).Update(obj)
+	assert.True(IsNotFound(err))
+
+	err = Default().Invoke(tx).WithStrict(true).Delete(obj)
+	assert.True(IsNotFound(err))
+
+	var verify benchObj
+	err = Default().Invoke(tx).WithStrict(true).Query(`select * from bench_object where id = $1`, obj.ID).Out(&verify)
+	assert.True(IsNotFound(err))
+
+	err = Default().DB(tx).WithStrict(true).Invoke().Query(`select * from bench_object where id = $1`, obj.ID).Out(&verify)
+	assert.True(IsNotFound(err))
+}
+
+func TestConnectionInitStatementsApplyToEveryConnection(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := NewConfigFromEnv().WithStatementTimeout(5 * time.Second).WithApplicationName("spiffy_test")
+	conn := NewFromConfig(cfg)
+	_, err := conn.Open()
+	assert.Nil(err)
+	defer conn.Close()
+
+	// hold several transactions open at once so each runs on its own connection.
+	var txs []*sql.Tx
+	for x := 0; x < 4; x++ {
+		tx, err := conn.Begin()
+		assert.Nil(err)
+		txs = append(txs, tx)
+	}
+	defer func() {
+		for _, tx := range txs {
+			tx.Rollback()
+		}
+	}()
+
+	for _, tx := range txs {
+		var statementTimeout, applicationName string
+		err = conn.QueryInTx("SHOW statement_timeout", tx).Scan(&statementTimeout)
+		assert.Nil(err)
+		assert.Equal("5s", statementTimeout)
+
+		err = conn.QueryInTx("SHOW application_name", tx).Scan(&applicationName)
+		assert.Nil(err)
+		assert.Equal("spiffy_test", applicationName)
+	}
+}
@@ -0,0 +1,180 @@
+package spiffy
+
+import (
+	"context"
+	"database/sql/driver"
+
+	exception "github.com/blendlabs/go-exception"
+	"github.com/lib/pq"
+)
+
+// NewConnector returns a driver connector for a config.
+// It runs the config's init statements (see `Config.CreateInitStatements`) on every new connection the pool opens,
+// so session settings like the search path apply no matter which pooled connection a statement runs on.
+func NewConnector(cfg *Config) (driver.Connector, error) {
+	base, err := pq.NewConnector(cfg.CreateDSN())
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return &Connector{
+		base:           base,
+		initStatements: cfg.CreateInitStatements(),
+	}, nil
+}
+
+// Connector is a driver connector that initializes new connections.
+// Its connections report when transactions begun by a `Connection` end, however they are ended, for transaction
+// lifecycle events.
+type Connector struct {
+	base           driver.Connector
+	initStatements []string
+}
+
+// InitStatements returns the statements run on every new connection.
+func (c *Connector) InitStatements() []string {
+	return c.initStatements
+}
+
+// Connect implements driver.Connector.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range c.initStatements {
+		if err = execDriverConn(ctx, conn, statement); err != nil {
+			conn.Close()
+			return nil, exception.Wrap(err)
+		}
+	}
+	return &connectorConn{Conn: conn}, nil
+}
+
+// Driver implements driver.Connector.
+func (c *Connector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// execDriverConn runs a statement without arguments directly on a driver connection.
+func execDriverConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, isExecer := conn.(driver.ExecerContext); isExecer {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		return err
+	}
+
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// connectorConn is a driver connection opened by a `Connector`.
+// It observes the end of transactions begun with a `txHook` in their context, and passes everything else through,
+// including the optional driver interfaces the underlying connection implements.
+type connectorConn struct {
+	driver.Conn
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (cc *connectorConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if beginner, isBeginner := cc.Conn.(driver.ConnBeginTx); isBeginner {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		if opts.ReadOnly || opts.Isolation != driver.IsolationLevel(0) {
+			return nil, exception.New("driver does not support transaction options")
+		}
+		tx, err = cc.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hook := getTxHook(ctx); hook != nil {
+		return &connectorTx{Tx: tx, hook: hook}, nil
+	}
+	return tx, nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (cc *connectorConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, isPreparer := cc.Conn.(driver.ConnPrepareContext); isPreparer {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return cc.Conn.Prepare(query)
+}
+
+// ExecContext implements driver.ExecerContext.
+func (cc *connectorConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, isExecer := cc.Conn.(driver.ExecerContext); isExecer {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// QueryContext implements driver.QueryerContext.
+func (cc *connectorConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, isQueryer := cc.Conn.(driver.QueryerContext); isQueryer {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// Ping implements driver.Pinger.
+func (cc *connectorConn) Ping(ctx context.Context) error {
+	if pinger, isPinger := cc.Conn.(driver.Pinger); isPinger {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (cc *connectorConn) ResetSession(ctx context.Context) error {
+	if resetter, isResetter := cc.Conn.(driver.SessionResetter); isResetter {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (cc *connectorConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, isChecker := cc.Conn.(driver.NamedValueChecker); isChecker {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// connectorTx is a driver transaction that reports to its hook when it's committed or rolled back.
+type connectorTx struct {
+	driver.Tx
+	hook *txHook
+}
+
+// Commit implements driver.Tx.
+func (ct *connectorTx) Commit() error {
+	err := ct.Tx.Commit()
+	ct.hook.ended(FlagTxCommit, err)
+	return err
+}
+
+// Rollback implements driver.Tx.
+func (ct *connectorTx) Rollback() error {
+	err := ct.Tx.Rollback()
+	ct.hook.ended(FlagTxRollback, err)
+	return err
+}
+
+// compile time assertion that the connection keeps the optional driver interfaces `database/sql` looks for.
+var (
+	_ driver.ConnBeginTx        = (*connectorConn)(nil)
+	_ driver.ConnPrepareContext = (*connectorConn)(nil)
+	_ driver.ExecerContext      = (*connectorConn)(nil)
+	_ driver.QueryerContext     = (*connectorConn)(nil)
+	_ driver.Pinger             = (*connectorConn)(nil)
+	_ driver.SessionResetter    = (*connectorConn)(nil)
+	_ driver.NamedValueChecker  = (*connectorConn)(nil)
+)
@@ -22,6 +22,7 @@ type DB struct {
 	tx         *sql.Tx
 	err        error
 	fireEvents bool
+	strict     bool
 }
 
 // WithCtx sets the db context.
@@ -46,6 +47,17 @@ func (db *DB) WithFireEvents(flag bool) *DB {
 	return db
 }
 
+// Strict returns if strict mode is enabled.
+func (db *DB) Strict() bool {
+	return db.strict
+}
+
+// WithStrict sets the `Strict` property.
+func (db *DB) WithStrict(flag bool) *DB {
+	db.strict = flag
+	return db
+}
+
 // WithConn sets the connection for the context.
 func (db *DB) WithConn(conn *Connection) *DB {
 	db.conn = conn
@@ -61,7 +73,7 @@ func (db *DB) Conn() *Connection {
 // The order precedence of the three main transaction sources are as follows:
 // - InTx(...) transaction arguments will be used above everything else
 // - an existing transaction on the context (i.e. if you call `.InTx().InTx()`)
-// - beginning a new transaction with the connection
+// - beginning a new transaction with the connection, bound to the context's ctx if it has one
 func (db *DB) InTx(txs ...*sql.Tx) *DB {
 	if len(txs) > 0 {
 		db.tx = txs[0]
@@ -74,7 +86,7 @@ func (db *DB) InTx(txs ...*sql.Tx) *DB {
 		db.err = exception.Newf(connectionErrorMessage)
 		return db
 	}
-	db.tx, db.err = db.conn.Begin()
+	db.tx, db.err = db.conn.BeginContext(db.ctx)
 	return db
 }
 
@@ -83,20 +95,26 @@ func (db *DB) Tx() *sql.Tx {
 	return db.tx
 }
 
-// Commit calls `Commit()` on the underlying transaction.
+// Commit commits the underlying transaction through the connection, which triggers a `db.tx.commit` event.
 func (db *DB) Commit() error {
 	if db.tx == nil {
 		return nil
 	}
-	return db.tx.Commit()
+	if db.conn == nil {
+		return db.tx.Commit()
+	}
+	return db.conn.Commit(db.tx)
 }
 
-// Rollback calls `Rollback()` on the underlying transaction.
+// Rollback rolls back the underlying transaction through the connection, which triggers a `db.tx.rollback` event.
 func (db *DB) Rollback() error {
 	if db.tx == nil {
 		return nil
 	}
-	return db.tx.Rollback()
+	if db.conn == nil {
+		return db.tx.Rollback()
+	}
+	return db.conn.Rollback(db.tx)
 }
 
 // Err returns the carried error.
@@ -106,5 +124,109 @@ func (db *DB) Err() error {
 
 // Invoke starts a new invocation.
 func (db *DB) Invoke() *Invocation {
-	return &Invocation{conn: db.conn, ctx: db.ctx, tx: db.tx, err: db.err, fireEvents: db.fireEvents}
+	return &Invocation{conn: db.conn, ctx: db.ctx, tx: db.tx, err: db.err, fireEvents: db.fireEvents, strict: db.strict}
+}
+
+// Begin returns a copy of the context bound to a new transaction from the connection, begun with the context's ctx if it has one.
+func (db *DB) Begin() (DataAccessContext, error) {
+	if db.conn == nil {
+		return nil, exception.Newf(connectionErrorMessage)
+	}
+	tx, err := db.conn.BeginContext(db.ctx)
+	if err != nil {
+		return nil, err
+	}
+	copied := *db
+	copied.tx = tx
+	return &copied, nil
+}
+
+// Exec runs a statement.
+func (db *DB) Exec(statement string, args ...interface{}) error {
+	return db.Invoke().Exec(statement, args...)
+}
+
+// Query runs a statement and returns its results.
+func (db *DB) Query(statement string, args ...interface{}) Results {
+	return db.Invoke().Query(statement, args...)
+}
+
+// Get returns a given object based on a group of primary key ids.
+func (db *DB) Get(object DatabaseMapped, ids ...interface{}) error {
+	return db.Invoke().Get(object, ids...)
+}
+
+// GetAll returns all rows of an object mapped table.
+func (db *DB) GetAll(collection interface{}) error {
+	return db.Invoke().GetAll(collection)
+}
+
+// Create writes an object to the database.
+func (db *DB) Create(object DatabaseMapped) error {
+	return db.Invoke().Create(object)
+}
+
+// CreateIfNotExists writes an object to the database if it does not already exist.
+func (db *DB) CreateIfNotExists(object DatabaseMapped) error {
+	return db.Invoke().CreateIfNotExists(object)
+}
+
+// CreateMany writes many objects to the database in a single insert.
+func (db *DB) CreateMany(objects interface{}) error {
+	return db.Invoke().CreateMany(objects)
+}
+
+// Update updates an object by its primary keys.
+func (db *DB) Update(object DatabaseMapped) error {
+	return db.Invoke().Update(object)
+}
+
+// Upsert inserts the object if it doesn't exist already (as defined by its primary keys) or updates it.
+func (db *DB) Upsert(object DatabaseMapped) error {
+	return db.Invoke().Upsert(object)
+}
+
+// Exists returns a bool if a given object exists (utilizing the primary key columns if they exist).
+func (db *DB) Exists(object DatabaseMapped) (bool, error) {
+	return db.Invoke().Exists(object)
+}
+
+// Delete deletes an object from the database.
+func (db *DB) Delete(object DatabaseMapped) error {
+	return db.Invoke().Delete(object)
+}
+
+// Truncate completely empties a table in a single command.
+func (db *DB) Truncate(object DatabaseMapped) error {
+	return db.Invoke().Truncate(object)
+}
+
+// CopyIn bulk loads a slice of objects into their table with `COPY FROM STDIN`.
+func (db *DB) CopyIn(collection interface{}) error {
+	return db.Invoke().CopyIn(collection)
+}
+
+// UpdateWithResult updates an object by its primary keys and returns the result.
+func (db *DB) UpdateWithResult(object DatabaseMapped) (sql.Result, error) {
+	return db.Invoke().UpdateWithResult(object)
+}
+
+// UpsertWith inserts the object or resolves the conflict as described by `conflict`, returning if the row was inserted, updated or skipped.
+func (db *DB) UpsertWith(object DatabaseMapped, conflict *Conflict) (UpsertResult, error) {
+	return db.Invoke().UpsertWith(object, conflict)
+}
+
+// UpsertMany inserts or updates a slice of objects in batched statements, returning the number of rows affected.
+func (db *DB) UpsertMany(objects interface{}) (int64, error) {
+	return db.Invoke().UpsertMany(objects)
+}
+
+// DeleteWithResult deletes an object from the database and returns the result.
+func (db *DB) DeleteWithResult(object DatabaseMapped) (sql.Result, error) {
+	return db.Invoke().DeleteWithResult(object)
+}
+
+// DeleteMany deletes a slice of objects by their primary keys in batched statements, returning the number of rows affected.
+func (db *DB) DeleteMany(objects interface{}) (int64, error) {
+	return db.Invoke().DeleteMany(objects)
 }
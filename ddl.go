@@ -0,0 +1,201 @@
+package spiffy
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+var (
+	ddlTimeType        = reflect.TypeOf(time.Time{})
+	ddlUUIDType        = reflect.TypeOf(UUID(nil))
+	ddlNullStringType  = reflect.TypeOf(sql.NullString{})
+	ddlNullInt64Type   = reflect.TypeOf(sql.NullInt64{})
+	ddlNullInt32Type   = reflect.TypeOf(sql.NullInt32{})
+	ddlNullFloat64Type = reflect.TypeOf(sql.NullFloat64{})
+	ddlNullBoolType    = reflect.TypeOf(sql.NullBool{})
+	ddlNullTimeType    = reflect.TypeOf(sql.NullTime{})
+)
+
+// CreateTableDDL returns the statements that create a mapped object's table; a `CREATE TABLE` statement with the
+// columns, primary key and unique constraint, followed by a `CREATE INDEX` statement for each index.
+//
+// Column data types are inferred from the field types unless they're set with the `db_type` tag, and columns are
+// `NOT NULL` unless they're tagged `nullable`, are pointers or are `sql.Null*` types. The `db_default` tag sets a column's default
+// expression, and the `db_index` tag indexes a column, where columns with the same index name share a multi-column index, ex:
+//
+//	type Document struct {
+//		ID         int       `db:"id,pk,serial"`
+//		OwnerID    int       `db:"owner_id" db_index:"ix_document_owner"`
+//		CreatedUTC time.Time `db:"created_utc,default" db_default:"now()"`
+//		Contents   string    `db:"contents" db_type:"varchar(4096)"`
+//	}
+func CreateTableDDL(object DatabaseMapped) ([]string, error) {
+	tableName := TableName(object)
+	cols := getCachedColumnCollectionFromInstance(object)
+	if cols.Len() == 0 {
+		return nil, exception.Newf("`%s` has no mapped columns", tableName)
+	}
+
+	var definitions []string
+	for _, col := range cols.Columns() {
+		definition, err := ColumnDefinition(col)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+
+	if pks := cols.PrimaryKeys(); pks.Len() > 0 {
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT pk_%s PRIMARY KEY (%s)", tableName, strings.Join(pks.ColumnNames(), ", ")))
+	}
+	if uks := cols.UniqueKeys(); uks.Len() > 0 {
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT uk_%s UNIQUE (%s)", tableName, strings.Join(uks.ColumnNames(), ", ")))
+	}
+
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", tableName, strings.Join(definitions, ",\n\t")),
+	}
+
+	for _, index := range TableIndexes(object) {
+		statements = append(statements, index.Statement())
+	}
+	return statements, nil
+}
+
+// ColumnDefinition returns the definition of a column within a `CREATE TABLE` or `ALTER TABLE ... ADD COLUMN` statement.
+func ColumnDefinition(col Column) (string, error) {
+	dataType, err := ColumnDataType(col)
+	if err != nil {
+		return "", err
+	}
+
+	definition := fmt.Sprintf("%s %s", col.ColumnName, dataType)
+	if col.IsPrimaryKey || !ColumnIsNullable(col) {
+		definition = definition + " NOT NULL"
+	}
+	if len(col.DefaultValue) > 0 {
+		definition = definition + " DEFAULT " + col.DefaultValue
+	}
+	return definition, nil
+}
+
+// TableIndex is an index on a mapped object's table, declared with the `db_index` tag.
+type TableIndex struct {
+	Table   string
+	Name    string
+	Columns []string
+}
+
+// Statement returns the `CREATE INDEX` statement for the index.
+func (ti TableIndex) Statement() string {
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", ti.Name, ti.Table, strings.Join(ti.Columns, ", "))
+}
+
+// TableIndexes returns the indexes declared on a mapped object's columns with the `db_index` tag, in column order.
+// Columns without an index name get their own index, named `ix_<table>_<column>`.
+func TableIndexes(object DatabaseMapped) []TableIndex {
+	tableName := TableName(object)
+
+	var indexes []TableIndex
+	positions := map[string]int{}
+	for _, col := range getCachedColumnCollectionFromInstance(object).Columns() {
+		if !col.IsIndexed {
+			continue
+		}
+		indexName := col.IndexName
+		if len(indexName) == 0 {
+			indexName = fmt.Sprintf("ix_%s_%s", tableName, col.ColumnName)
+		}
+		position, hasIndex := positions[indexName]
+		if !hasIndex {
+			position = len(indexes)
+			positions[indexName] = position
+			indexes = append(indexes, TableIndex{Table: tableName, Name: indexName})
+		}
+		indexes[position].Columns = append(indexes[position].Columns, col.ColumnName)
+	}
+	return indexes
+}
+
+// ColumnIsNullable returns if a column is nullable in generated DDL; if it's tagged `nullable`, is a pointer or is a `sql.Null*` type.
+func ColumnIsNullable(col Column) bool {
+	if col.IsNullable || col.FieldType.Kind() == reflect.Ptr {
+		return true
+	}
+	switch col.FieldType {
+	case ddlNullStringType, ddlNullInt64Type, ddlNullInt32Type, ddlNullFloat64Type, ddlNullBoolType, ddlNullTimeType:
+		return true
+	}
+	return false
+}
+
+// ColumnDataType returns the postgres data type of a column; either the `db_type` tag, or a type inferred from the field type.
+func ColumnDataType(col Column) (string, error) {
+	if len(col.DataType) > 0 {
+		return col.DataType, nil
+	}
+	if col.IsJSON {
+		return "jsonb", nil
+	}
+
+	t := col.FieldType
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if col.IsSerial {
+		switch t.Kind() {
+		case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint, reflect.Uint32:
+			return "bigserial", nil
+		case reflect.Int32, reflect.Int16, reflect.Int8, reflect.Uint16, reflect.Uint8:
+			return "serial", nil
+		}
+		return "", exception.Newf("serial column `%s` is not an integer", col.ColumnName)
+	}
+
+	switch t {
+	case ddlTimeType, ddlNullTimeType:
+		return "timestamp", nil
+	case ddlUUIDType:
+		return "uuid", nil
+	case ddlNullStringType:
+		return "text", nil
+	case ddlNullInt64Type:
+		return "bigint", nil
+	case ddlNullInt32Type:
+		return "integer", nil
+	case ddlNullFloat64Type:
+		return "double precision", nil
+	case ddlNullBoolType:
+		return "boolean", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint", nil
+	case reflect.Int32, reflect.Uint16:
+		return "integer", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint", nil
+	case reflect.Uint, reflect.Uint64:
+		return "numeric(20)", nil
+	case reflect.Float32:
+		return "real", nil
+	case reflect.Float64:
+		return "double precision", nil
+	case reflect.String:
+		return "text", nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytea", nil
+		}
+	}
+	return "", exception.Newf("cannot infer a data type for column `%s` of type `%s`; set it with the `db_type` tag", col.ColumnName, col.FieldType)
+}
@@ -0,0 +1,80 @@
+package spiffy
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type ddlObj struct {
+	ID         int64             `db:"id,pk,serial"`
+	OwnerID    int               `db:"owner_id" db_index:"ix_ddl_obj_owner"`
+	Kind       string            `db:"kind,unique" db_index:"ix_ddl_obj_owner"`
+	Slug       string            `db:"slug,unique"`
+	Contents   string            `db:"contents" db_type:"varchar(4096)"`
+	Amount     float32           `db:"amount" db_index:""`
+	Archived   *time.Time        `db:"archived_utc"`
+	Note       sql.NullString    `db:"note"`
+	CreatedUTC time.Time         `db:"created_utc,default" db_default:"now()"`
+	Labels     map[string]string `db:"labels,json"`
+	Ignored    string            `db:"-"`
+}
+
+func (do ddlObj) TableName() string {
+	return "ddl_obj"
+}
+
+type ddlInvalidObj struct {
+	ID     int               `db:"id,pk"`
+	Labels map[string]string `db:"labels"`
+}
+
+func TestCreateTableDDL(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, err := CreateTableDDL(ddlObj{})
+	assert.Nil(err)
+	assert.Len(statements, 3)
+	assert.Equal(strings.Join([]string{
+		"CREATE TABLE ddl_obj (",
+		"\tid bigserial NOT NULL,",
+		"\towner_id bigint NOT NULL,",
+		"\tkind text NOT NULL,",
+		"\tslug text NOT NULL,",
+		"\tcontents varchar(4096) NOT NULL,",
+		"\tamount real NOT NULL,",
+		"\tarchived_utc timestamp,",
+		"\tnote text,",
+		"\tcreated_utc timestamp NOT NULL DEFAULT now(),",
+		"\tlabels jsonb NOT NULL,",
+		"\tCONSTRAINT pk_ddl_obj PRIMARY KEY (id),",
+		"\tCONSTRAINT uk_ddl_obj UNIQUE (kind, slug)",
+		")",
+	}, "\n"), statements[0])
+	assert.Equal("CREATE INDEX ix_ddl_obj_owner ON ddl_obj (owner_id, kind)", statements[1])
+	assert.Equal("CREATE INDEX ix_ddl_obj_amount ON ddl_obj (amount)", statements[2])
+
+	_, err = CreateTableDDL(ddlInvalidObj{})
+	assert.NotNil(err)
+}
+
+func TestCreateTableDDLApplies(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	statements, err := CreateTableDDL(ddlObj{})
+	assert.Nil(err)
+	for _, statement := range statements {
+		assert.Nil(Default().ExecInTx(statement, tx))
+	}
+
+	obj := ddlObj{OwnerID: 1, Kind: "test", Slug: "test", Labels: map[string]string{}}
+	assert.Nil(Default().CreateInTx(&obj, tx))
+	assert.NotZero(obj.ID)
+	assert.False(obj.CreatedUTC.IsZero())
+}
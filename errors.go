@@ -0,0 +1,53 @@
+package spiffy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// pqErrorCodeFeatureNotSupported is the error code postgres uses for stale cached plans.
+	pqErrorCodeFeatureNotSupported = "0A000"
+	// pqErrorMessageStalePlan is the message postgres uses for stale cached plans.
+	pqErrorMessageStalePlan = "cached plan must not change result type"
+	// sqlErrorMessageStatementClosed is the message database/sql uses for statements that were closed before they were used.
+	sqlErrorMessageStatementClosed = "sql: statement is closed"
+)
+
+// NotFoundError is returned in strict mode when a statement that should match a row doesn't match any rows.
+type NotFoundError struct {
+	// Label is the statement label, ex: `my_table_update`.
+	Label string
+}
+
+// Error implements error.
+func (nfe *NotFoundError) Error() string {
+	if len(nfe.Label) > 0 {
+		return fmt.Sprintf("no rows found for `%s`", nfe.Label)
+	}
+	return "no rows found"
+}
+
+// IsNotFound returns if an error is a `NotFoundError`.
+func IsNotFound(err error) bool {
+	var nfe *NotFoundError
+	return errors.As(err, &nfe)
+}
+
+// IsStalePlan returns if an error is because a prepared statement's plan is stale,
+// which happens when the tables it references have changed since it was prepared.
+func IsStalePlan(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pqErrorCodeFeatureNotSupported && pqErr.Message == pqErrorMessageStalePlan
+}
+
+// isStatementClosed returns if an error is because a statement was closed before it was used,
+// which happens when a cached statement is evicted or invalidated while another goroutine holds it.
+func isStatementClosed(err error) bool {
+	return err != nil && err.Error() == sqlErrorMessageStatementClosed
+}
@@ -0,0 +1,31 @@
+package spiffy
+
+import (
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/lib/pq"
+)
+
+func TestIsNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsNotFound(&NotFoundError{Label: "test"}))
+	assert.True(IsNotFound(fmt.Errorf("outer: %w", &NotFoundError{})))
+	assert.False(IsNotFound(fmt.Errorf("test")))
+	assert.False(IsNotFound(nil))
+
+	assert.Equal("no rows found for `test`", (&NotFoundError{Label: "test"}).Error())
+}
+
+func TestIsStalePlan(t *testing.T) {
+	assert := assert.New(t)
+
+	stale := &pq.Error{Code: "0A000", Message: "cached plan must not change result type"}
+	assert.True(IsStalePlan(stale))
+	assert.True(IsStalePlan(fmt.Errorf("outer: %w", stale)))
+	assert.False(IsStalePlan(&pq.Error{Code: "0A000", Message: "some other unsupported feature"}))
+	assert.False(IsStalePlan(fmt.Errorf("cached plan must not change result type")))
+	assert.False(IsStalePlan(nil))
+}
@@ -3,6 +3,7 @@ package spiffy
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"time"
 
 	logger "github.com/blendlabs/go-logger"
@@ -14,8 +15,33 @@ const (
 
 	// FlagQuery is a logger.EventFlag
 	FlagQuery logger.Flag = "db.query"
+
+	// FlagTxBegin is a logger.EventFlag
+	FlagTxBegin logger.Flag = "db.tx.begin"
+
+	// FlagTxCommit is a logger.EventFlag
+	FlagTxCommit logger.Flag = "db.tx.commit"
+
+	// FlagTxRollback is a logger.EventFlag
+	FlagTxRollback logger.Flag = "db.tx.rollback"
+
+	// FlagSlowQuery is a logger.EventFlag
+	FlagSlowQuery logger.Flag = "db.slow_query"
 )
 
+// ArgRedactor returns the form of a statement argument that is written to events.
+type ArgRedactor func(label string, index int, arg interface{}) interface{}
+
+// IncludeArgs is an `ArgRedactor` that writes arguments to events as they are.
+func IncludeArgs(label string, index int, arg interface{}) interface{} {
+	return arg
+}
+
+// RedactArgs is an `ArgRedactor` that writes a placeholder in place of every argument.
+func RedactArgs(label string, index int, arg interface{}) interface{} {
+	return "[redacted]"
+}
+
 // NewEvent creates a new logger event.
 func NewEvent(flag logger.Flag, label string, elapsed time.Duration, err error) Event {
 	return Event{
@@ -27,6 +53,89 @@ func NewEvent(flag logger.Flag, label string, elapsed time.Duration, err error)
 	}
 }
 
+// eventDetails are the optional details of a statement, collected as it runs.
+type eventDetails struct {
+	args            []interface{}
+	hasRowsAffected bool
+	rowsAffected    int64
+	hasRowsReturned bool
+	rowsReturned    int64
+	cached          bool
+	txID            string
+	contextValues   map[string]interface{}
+	prepareElapsed  time.Duration
+	executeElapsed  time.Duration
+	scanElapsed     time.Duration
+}
+
+// addRowsAffected adds to the rows affected.
+func (ed *eventDetails) addRowsAffected(rowsAffected int64) {
+	ed.hasRowsAffected = true
+	ed.rowsAffected = ed.rowsAffected + rowsAffected
+}
+
+// addRowsReturned adds to the rows returned.
+func (ed *eventDetails) addRowsReturned(rowsReturned int64) {
+	ed.hasRowsReturned = true
+	ed.rowsReturned = ed.rowsReturned + rowsReturned
+}
+
+// writeText writes the details that are set as `key=value` pairs.
+func (ed eventDetails) writeText(buf *bytes.Buffer) {
+	if ed.hasRowsAffected {
+		buf.WriteString(fmt.Sprintf(" rowsAffected=%d", ed.rowsAffected))
+	}
+	if ed.hasRowsReturned {
+		buf.WriteString(fmt.Sprintf(" rowsReturned=%d", ed.rowsReturned))
+	}
+	if ed.cached {
+		buf.WriteString(" cached=true")
+	}
+	if len(ed.txID) > 0 {
+		buf.WriteString(" tx=" + ed.txID)
+	}
+	if ed.prepareElapsed > 0 {
+		buf.WriteString(fmt.Sprintf(" prepare=%v", ed.prepareElapsed))
+	}
+	if ed.executeElapsed > 0 {
+		buf.WriteString(fmt.Sprintf(" execute=%v", ed.executeElapsed))
+	}
+	if ed.scanElapsed > 0 {
+		buf.WriteString(fmt.Sprintf(" scan=%v", ed.scanElapsed))
+	}
+	if len(ed.contextValues) > 0 {
+		keys := make([]string, 0, len(ed.contextValues))
+		for key := range ed.contextValues {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buf.WriteString(fmt.Sprintf(" %s=%v", key, ed.contextValues[key]))
+		}
+	}
+}
+
+// writeJSON adds the details that are set to a json object.
+func (ed eventDetails) writeJSON(obj logger.JSONObj) logger.JSONObj {
+	if ed.hasRowsAffected {
+		obj["rowsAffected"] = ed.rowsAffected
+	}
+	if ed.hasRowsReturned {
+		obj["rowsReturned"] = ed.rowsReturned
+	}
+	obj["cached"] = ed.cached
+	if len(ed.txID) > 0 {
+		obj["txID"] = ed.txID
+	}
+	if len(ed.contextValues) > 0 {
+		obj["contextValues"] = ed.contextValues
+	}
+	obj["prepareElapsed"] = logger.Milliseconds(ed.prepareElapsed)
+	obj["executeElapsed"] = logger.Milliseconds(ed.executeElapsed)
+	obj["scanElapsed"] = logger.Milliseconds(ed.scanElapsed)
+	return obj
+}
+
 // NewEventListener returns a new listener for spiffy events.
 func NewEventListener(listener func(e Event)) logger.Listener {
 	return func(e logger.Event) {
@@ -44,6 +153,8 @@ type Event struct {
 	queryBody  string
 	elapsed    time.Duration
 	err        error
+
+	eventDetails
 }
 
 // Flag returns the event flag.
@@ -71,21 +182,75 @@ func (e Event) Err() error {
 	return e.err
 }
 
+// Args returns the statement arguments, as written by the connection's `ArgRedactor`.
+// They are empty unless the connection includes arguments in events.
+func (e Event) Args() []interface{} {
+	return e.args
+}
+
+// RowsAffected returns the number of rows affected by the statement, or -1 if it is unknown.
+func (e Event) RowsAffected() int64 {
+	if !e.hasRowsAffected {
+		return -1
+	}
+	return e.rowsAffected
+}
+
+// RowsReturned returns the number of rows read from the statement's results, or -1 if it is unknown.
+func (e Event) RowsReturned() int64 {
+	if !e.hasRowsReturned {
+		return -1
+	}
+	return e.rowsReturned
+}
+
+// Cached returns if the statement was prepared through the statement cache.
+func (e Event) Cached() bool {
+	return e.cached
+}
+
+// TxID returns an identifier for the transaction the statement ran in, or an empty string if it did not run in a transaction.
+// The identifier is unique among open transactions.
+func (e Event) TxID() string {
+	return e.txID
+}
+
+// ContextValues returns the values of the connection's event context keys, if they were set on the statement's context.
+func (e Event) ContextValues() map[string]interface{} {
+	return e.contextValues
+}
+
+// PrepareElapsed returns the time spent preparing the statement.
+func (e Event) PrepareElapsed() time.Duration {
+	return e.prepareElapsed
+}
+
+// ExecuteElapsed returns the time spent executing the statement.
+func (e Event) ExecuteElapsed() time.Duration {
+	return e.executeElapsed
+}
+
+// ScanElapsed returns the time spent reading the statement's results.
+func (e Event) ScanElapsed() time.Duration {
+	return e.scanElapsed
+}
+
 // WriteText writes the event text to the output.
 func (e Event) WriteText(tf logger.TextFormatter, buf *bytes.Buffer) {
 	buf.WriteString(fmt.Sprintf("(%v) ", e.elapsed))
 	if len(e.queryLabel) > 0 {
 		buf.WriteString(e.queryLabel)
 	}
+	e.eventDetails.writeText(buf)
 	buf.WriteRune(logger.RuneNewline)
 }
 
 // WriteJSON implements logger.JSONWritable.
 func (e Event) WriteJSON() logger.JSONObj {
-	return logger.JSONObj{
+	return e.eventDetails.writeJSON(logger.JSONObj{
 		"queryLabel":            e.queryLabel,
 		logger.JSONFieldElapsed: logger.Milliseconds(e.elapsed),
-	}
+	})
 }
 
 // NewStatementEvent creates a new logger event.
@@ -122,15 +287,213 @@ func (e StatementEvent) WriteText(tf logger.TextFormatter, buf *bytes.Buffer) {
 	if len(e.queryLabel) > 0 {
 		buf.WriteString(e.queryLabel)
 	}
+	e.eventDetails.writeText(buf)
 	buf.WriteRune(logger.RuneNewline)
 	buf.WriteString(e.queryBody)
+	if len(e.args) > 0 {
+		buf.WriteRune(logger.RuneNewline)
+		buf.WriteString(fmt.Sprintf("args: %v", e.args))
+	}
 }
 
 // WriteJSON implements logger.JSONWritable.
 func (e StatementEvent) WriteJSON() logger.JSONObj {
-	return logger.JSONObj{
+	obj := e.eventDetails.writeJSON(logger.JSONObj{
 		"queryLabel":            e.queryLabel,
 		"queryBody":             e.queryBody,
 		logger.JSONFieldElapsed: logger.Milliseconds(e.elapsed),
+	})
+	if len(e.args) > 0 {
+		obj["args"] = e.args
+	}
+	return obj
+}
+
+// NewTxEvent creates a new transaction lifecycle event.
+func NewTxEvent(flag logger.Flag, txID string, elapsed time.Duration, statements int64, err error) TxEvent {
+	return TxEvent{
+		flag:       flag,
+		ts:         time.Now().UTC(),
+		txID:       txID,
+		elapsed:    elapsed,
+		statements: statements,
+		err:        err,
+	}
+}
+
+// NewTxEventListener returns a new listener for spiffy transaction events.
+func NewTxEventListener(listener func(e TxEvent)) logger.Listener {
+	return func(e logger.Event) {
+		if typed, isTyped := e.(TxEvent); isTyped {
+			listener(typed)
+		}
+	}
+}
+
+// TxEvent is the event we trigger the logger with when a transaction begins, commits or rolls back.
+type TxEvent struct {
+	flag       logger.Flag
+	ts         time.Time
+	txID       string
+	elapsed    time.Duration
+	statements int64
+	err        error
+}
+
+// Flag returns the event flag.
+func (e TxEvent) Flag() logger.Flag {
+	return e.flag
+}
+
+// Timestamp returns the event timestamp.
+func (e TxEvent) Timestamp() time.Time {
+	return e.ts
+}
+
+// TxID returns the transaction identifier; see `TxID`.
+func (e TxEvent) TxID() string {
+	return e.txID
+}
+
+// Elapsed returns the time since the transaction began.
+func (e TxEvent) Elapsed() time.Duration {
+	return e.elapsed
+}
+
+// Statements returns the number of statements executed in the transaction.
+func (e TxEvent) Statements() int64 {
+	return e.statements
+}
+
+// Err returns the error from beginning, committing or rolling back the transaction.
+func (e TxEvent) Err() error {
+	return e.err
+}
+
+// WriteText writes the event text to the output.
+func (e TxEvent) WriteText(tf logger.TextFormatter, buf *bytes.Buffer) {
+	buf.WriteString(fmt.Sprintf("(%v) tx=%s statements=%d", e.elapsed, e.txID, e.statements))
+	if e.err != nil {
+		buf.WriteString(fmt.Sprintf(" err=%v", e.err))
+	}
+	buf.WriteRune(logger.RuneNewline)
+}
+
+// WriteJSON implements logger.JSONWritable.
+func (e TxEvent) WriteJSON() logger.JSONObj {
+	obj := logger.JSONObj{
+		"txID":                  e.txID,
+		"statements":            e.statements,
+		logger.JSONFieldElapsed: logger.Milliseconds(e.elapsed),
+	}
+	if e.err != nil {
+		obj[logger.JSONFieldErr] = e.err.Error()
+	}
+	return obj
+}
+
+// NewSlowQueryEvent creates a new slow query event.
+func NewSlowQueryEvent(label, query string, elapsed, threshold time.Duration) SlowQueryEvent {
+	return SlowQueryEvent{
+		flag:       FlagSlowQuery,
+		ts:         time.Now().UTC(),
+		queryLabel: label,
+		queryBody:  query,
+		elapsed:    elapsed,
+		threshold:  threshold,
+	}
+}
+
+// NewSlowQueryEventListener returns a new listener for spiffy slow query events.
+func NewSlowQueryEventListener(listener func(e SlowQueryEvent)) logger.Listener {
+	return func(e logger.Event) {
+		if typed, isTyped := e.(SlowQueryEvent); isTyped {
+			listener(typed)
+		}
+	}
+}
+
+// SlowQueryEvent is the event we trigger the logger with when a labeled statement takes longer than the slow query threshold.
+type SlowQueryEvent struct {
+	flag       logger.Flag
+	ts         time.Time
+	queryLabel string
+	queryBody  string
+	elapsed    time.Duration
+	threshold  time.Duration
+	explain    string
+	explainErr error
+}
+
+// Flag returns the event flag.
+func (e SlowQueryEvent) Flag() logger.Flag {
+	return e.flag
+}
+
+// Timestamp returns the event timestamp.
+func (e SlowQueryEvent) Timestamp() time.Time {
+	return e.ts
+}
+
+// QueryLabel returns the query label.
+func (e SlowQueryEvent) QueryLabel() string {
+	return e.queryLabel
+}
+
+// QueryBody returns the query body.
+func (e SlowQueryEvent) QueryBody() string {
+	return e.queryBody
+}
+
+// Elapsed returns the elapsed time.
+func (e SlowQueryEvent) Elapsed() time.Duration {
+	return e.elapsed
+}
+
+// Threshold returns the slow query threshold the statement exceeded.
+func (e SlowQueryEvent) Threshold() time.Duration {
+	return e.threshold
+}
+
+// Explain returns the output of `EXPLAIN (FORMAT JSON)` for the statement, if it was captured.
+func (e SlowQueryEvent) Explain() string {
+	return e.explain
+}
+
+// ExplainErr returns the error from capturing the explain output, if any.
+func (e SlowQueryEvent) ExplainErr() error {
+	return e.explainErr
+}
+
+// WriteText writes the event text to the output.
+func (e SlowQueryEvent) WriteText(tf logger.TextFormatter, buf *bytes.Buffer) {
+	buf.WriteString(fmt.Sprintf("(%v > %v) ", e.elapsed, e.threshold))
+	buf.WriteString(e.queryLabel)
+	buf.WriteRune(logger.RuneNewline)
+	buf.WriteString(e.queryBody)
+	if len(e.explain) > 0 {
+		buf.WriteRune(logger.RuneNewline)
+		buf.WriteString(e.explain)
+	}
+	if e.explainErr != nil {
+		buf.WriteRune(logger.RuneNewline)
+		buf.WriteString(fmt.Sprintf("explain error: %v", e.explainErr))
+	}
+}
+
+// WriteJSON implements logger.JSONWritable.
+func (e SlowQueryEvent) WriteJSON() logger.JSONObj {
+	obj := logger.JSONObj{
+		"queryLabel":            e.queryLabel,
+		"queryBody":             e.queryBody,
+		"threshold":             logger.Milliseconds(e.threshold),
+		logger.JSONFieldElapsed: logger.Milliseconds(e.elapsed),
+	}
+	if len(e.explain) > 0 {
+		obj["explain"] = e.explain
+	}
+	if e.explainErr != nil {
+		obj["explainErr"] = e.explainErr.Error()
 	}
+	return obj
 }
@@ -0,0 +1,150 @@
+package spiffy
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+)
+
+type eventTestKey string
+
+func TestEventDetails(t *testing.T) {
+	assert := assert.New(t)
+
+	e := NewEvent(FlagQuery, "test_label", time.Millisecond, nil)
+	assert.Equal(int64(-1), e.RowsAffected())
+	assert.Equal(int64(-1), e.RowsReturned())
+
+	e.addRowsAffected(2)
+	e.addRowsAffected(3)
+	e.addRowsReturned(0)
+	e.cached = true
+	e.txID = "0x1"
+	e.contextValues = map[string]interface{}{"route": "/users", "requestID": "abc"}
+	e.prepareElapsed = 2 * time.Millisecond
+
+	assert.Equal(int64(5), e.RowsAffected())
+	assert.Equal(int64(0), e.RowsReturned())
+
+	buf := bytes.NewBuffer(nil)
+	e.WriteText(nil, buf)
+	assert.Equal("(1ms) test_label rowsAffected=5 rowsReturned=0 cached=true tx=0x1 prepare=2ms requestID=abc route=/users\n", buf.String())
+
+	obj := e.WriteJSON()
+	assert.Equal("test_label", obj["queryLabel"])
+	assert.Equal(int64(5), obj["rowsAffected"])
+	assert.Equal(int64(0), obj["rowsReturned"])
+	assert.Equal(true, obj["cached"])
+	assert.Equal("0x1", obj["txID"])
+	assert.NotNil(obj["contextValues"])
+}
+
+func TestStatementEventArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	e := NewStatementEvent(FlagExecute, "test_label", "select $1", time.Millisecond, nil)
+	e.args = []interface{}{"foo"}
+
+	buf := bytes.NewBuffer(nil)
+	e.WriteText(nil, buf)
+	assert.Equal("(1ms) test_label\nselect $1\nargs: [foo]", buf.String())
+	assert.Equal([]interface{}{"foo"}, e.WriteJSON()["args"])
+}
+
+func TestConnectionEventArgsAndContextValues(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := New()
+	assert.Nil(conn.redactArgs("test", []interface{}{"foo"}))
+
+	conn.WithEventArgs(RedactArgs)
+	assert.Equal([]interface{}{"[redacted]"}, conn.redactArgs("test", []interface{}{"foo"}))
+
+	conn.WithEventArgs(IncludeArgs)
+	assert.Equal([]interface{}{"foo"}, conn.redactArgs("test", []interface{}{"foo"}))
+
+	assert.Nil(conn.contextValues(context.Background()))
+	conn.WithEventContextKeys(eventTestKey("route"), eventTestKey("missing"))
+	ctx := context.WithValue(context.Background(), eventTestKey("route"), "/users")
+	assert.Equal(map[string]interface{}{"route": "/users"}, conn.contextValues(ctx))
+}
+
+func TestTxEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	e := NewTxEvent(FlagTxRollback, "0x1", time.Second, 3, fmt.Errorf("test"))
+	assert.Equal(FlagTxRollback, e.Flag())
+
+	buf := bytes.NewBuffer(nil)
+	e.WriteText(nil, buf)
+	assert.Equal("(1s) tx=0x1 statements=3 err=test\n", buf.String())
+
+	obj := e.WriteJSON()
+	assert.Equal("0x1", obj["txID"])
+	assert.Equal(int64(3), obj["statements"])
+	assert.Equal("test", obj["err"])
+}
+
+func TestConnectionTxLifecycle(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := NewFromEnv()
+	defer conn.Close()
+	conn.WithLogger(logger.New(FlagTxBegin, FlagTxCommit, FlagTxRollback))
+
+	tx, err := conn.Begin()
+	assert.Nil(err)
+	assert.Equal(1, conn.txs.len())
+
+	err = conn.ExecInTx("select 1", tx)
+	assert.Nil(err)
+	err = conn.ExecInTx("select 2", tx)
+	assert.Nil(err)
+
+	state, tracked := conn.txs.state[TxID(tx)]
+	assert.True(tracked)
+	assert.Equal(int64(2), state.statements)
+
+	assert.Nil(conn.Commit(tx))
+	assert.Zero(conn.txs.len())
+	assert.Equal(sql.ErrTxDone, conn.Rollback(tx))
+}
+
+func TestConnectionTxLifecycleEndedDirectly(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := NewFromEnv()
+	defer conn.Close()
+	log := logger.New(FlagTxBegin, FlagTxCommit, FlagTxRollback)
+	conn.WithLogger(log)
+
+	ended := make(chan TxEvent, 2)
+	log.Listen(FlagTxCommit, "test", NewTxEventListener(func(e TxEvent) { ended <- e }))
+	log.Listen(FlagTxRollback, "test", NewTxEventListener(func(e TxEvent) { ended <- e }))
+
+	tx, err := conn.Begin()
+	assert.Nil(err)
+	assert.Nil(conn.ExecInTx("select 1", tx))
+	assert.Nil(tx.Commit())
+	assert.Zero(conn.txs.len())
+
+	e := <-ended
+	assert.Equal(FlagTxCommit, e.Flag())
+	assert.Equal(TxID(tx), e.TxID())
+	assert.Equal(int64(1), e.Statements())
+
+	tx, err = conn.Begin()
+	assert.Nil(err)
+	assert.Nil(tx.Rollback())
+	assert.Zero(conn.txs.len())
+
+	e = <-ended
+	assert.Equal(FlagTxRollback, e.Flag())
+	assert.Equal(TxID(tx), e.TxID())
+}
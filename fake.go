@@ -0,0 +1,613 @@
+package spiffy
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+// NewFake returns a new in-memory `DataAccessContext` for unit tests.
+func NewFake() *Fake {
+	return &Fake{
+		state: &fakeState{lock: &sync.Mutex{}},
+	}
+}
+
+// Fake is an in-memory `DataAccessContext` that records the statements it is asked to run and returns scripted results.
+//
+// Statements are identified by label: mapped object methods use the same labels as `Invocation`,
+// ex: `users_get`, `users_create` or, if zero valued `default` columns are left out, `users_create_omit_created_utc`; raw statements passed to `Exec` and `Query` are labeled by the statement itself,
+// and transactions are labeled `db.tx.begin`, `db.tx.commit` and `db.tx.rollback`.
+// `UpsertWith`, which is only labeled by `Invocation` when given a label, is labeled `users_upsert_with`.
+// Statements without an expectation succeed without returning any rows.
+type Fake struct {
+	state  *fakeState
+	tx     *fakeTx
+	strict bool
+}
+
+type fakeState struct {
+	lock         *sync.Mutex
+	statements   []FakeStatement
+	expectations []*FakeExpectation
+	txs          int
+}
+
+type fakeTx struct {
+	id   int
+	done bool
+}
+
+// FakeStatement is a statement recorded by a `Fake`.
+type FakeStatement struct {
+	Label string
+	// Statement is the statement passed to `Exec` or `Query`, or empty for mapped object methods and transactions.
+	Statement string
+	// Args are the arguments passed to `Exec` and `Query`, the ids passed to `Get`, or the objects passed to the other
+	// mapped object methods.
+	Args []interface{}
+	// TxID is the transaction the statement ran in, starting at 1, or 0 if it didn't run in a transaction.
+	TxID int
+	// Expected is if the statement matched an expectation.
+	Expected bool
+}
+
+// FakeExpectation is the scripted result of statements with a given label.
+type FakeExpectation struct {
+	label           string
+	results         []interface{}
+	err             error
+	rowsAffected    int64
+	hasRowsAffected bool
+	times           int
+	calls           int
+}
+
+// WithStrict sets if `Scan` and `Out` return a `NotFoundError` when there are no results, and `Update` and `Delete` return
+// one when they affect no rows, as they do in strict mode.
+func (f *Fake) WithStrict(flag bool) *Fake {
+	f.strict = flag
+	return f
+}
+
+// Expect adds an expectation for statements with a label and returns it for scripting.
+// Expectations for the same label are matched in the order they're added, until they've been called the number of times they expect.
+func (f *Fake) Expect(label string) *FakeExpectation {
+	f.state.lock.Lock()
+	defer f.state.lock.Unlock()
+	expectation := &FakeExpectation{label: label}
+	f.state.expectations = append(f.state.expectations, expectation)
+	return expectation
+}
+
+// Statements returns the recorded statements in the order they ran.
+func (f *Fake) Statements() []FakeStatement {
+	f.state.lock.Lock()
+	defer f.state.lock.Unlock()
+	return append([]FakeStatement(nil), f.state.statements...)
+}
+
+// Unexpected returns the recorded statements, other than transaction begins, commits and rollbacks, that didn't match an expectation.
+func (f *Fake) Unexpected() []FakeStatement {
+	f.state.lock.Lock()
+	defer f.state.lock.Unlock()
+	var unexpected []FakeStatement
+	for _, statement := range f.state.statements {
+		if !statement.Expected && !isFakeTxLabel(statement.Label) {
+			unexpected = append(unexpected, statement)
+		}
+	}
+	return unexpected
+}
+
+// Verify returns an error describing any expectations that weren't called the number of times they expect.
+func (f *Fake) Verify() error {
+	f.state.lock.Lock()
+	defer f.state.lock.Unlock()
+	var unmet []string
+	for _, expectation := range f.state.expectations {
+		if expectation.times == 0 && expectation.calls == 0 {
+			unmet = append(unmet, fmt.Sprintf("`%s` was not called", expectation.label))
+		} else if expectation.times > 0 && expectation.calls != expectation.times {
+			unmet = append(unmet, fmt.Sprintf("`%s` was called %d times, expected %d", expectation.label, expectation.calls, expectation.times))
+		}
+	}
+	if len(unmet) > 0 {
+		return exception.Newf("unmet expectations: %s", strings.Join(unmet, "; "))
+	}
+	return nil
+}
+
+// Reset clears the recorded statements and expectations.
+func (f *Fake) Reset() {
+	f.state.lock.Lock()
+	defer f.state.lock.Unlock()
+	f.state.statements = nil
+	f.state.expectations = nil
+}
+
+// Begin implements `DataAccessContext`, returning a fake bound to a new transaction that shares the recorded statements and expectations.
+func (f *Fake) Begin() (DataAccessContext, error) {
+	f.state.lock.Lock()
+	f.state.txs = f.state.txs + 1
+	tx := &fakeTx{id: f.state.txs}
+	f.state.lock.Unlock()
+
+	_, err := (&Fake{state: f.state, tx: tx}).run(string(FlagTxBegin), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Fake{state: f.state, tx: tx, strict: f.strict}, nil
+}
+
+// Commit implements `DataAccessContext`.
+func (f *Fake) Commit() error {
+	return f.endTx(string(FlagTxCommit))
+}
+
+// Rollback implements `DataAccessContext`.
+func (f *Fake) Rollback() error {
+	return f.endTx(string(FlagTxRollback))
+}
+
+// Exec implements `DataAccess`.
+func (f *Fake) Exec(statement string, args ...interface{}) error {
+	_, err := f.run(statement, statement, args)
+	return err
+}
+
+// CopyIn implements `DataAccess`, recording each object as an argument.
+func (f *Fake) CopyIn(collection interface{}) error {
+	_, err := f.run(fmt.Sprintf("%s_copy_in", TableNameByType(reflectSliceType(collection))), "", fakeElements(collection))
+	return err
+}
+
+// Query implements `DataAccessContext`.
+func (f *Fake) Query(statement string, args ...interface{}) Results {
+	results, err := f.run(statement, statement, args)
+	return &fakeResults{label: statement, results: results, err: err, strict: f.strict}
+}
+
+// Get implements `DataAccess`, copying the first scripted result, if any, into the object.
+func (f *Fake) Get(object DatabaseMapped, ids ...interface{}) error {
+	if ids == nil {
+		return exception.New("invalid `ids` parameter.")
+	}
+	results, err := f.run(fmt.Sprintf("%s_get", TableName(object)), "", ids)
+	if err != nil || len(results) == 0 {
+		return err
+	}
+	return fakeAssign(object, results[0])
+}
+
+// GetAll implements `DataAccess`, appending the scripted results to the collection.
+func (f *Fake) GetAll(collection interface{}) error {
+	results, err := f.run(fmt.Sprintf("%s_get_all", TableNameByType(reflectSliceType(collection))), "", nil)
+	if err != nil {
+		return err
+	}
+	return fakeAppend(collection, results)
+}
+
+// Create implements `DataAccess`, copying the first scripted result, if any, into the object, ex: to set serial columns.
+func (f *Fake) Create(object DatabaseMapped) error {
+	_, omitted := getCachedColumnCollectionFromInstance(object).NotReadOnly().NotSerials().NotZeroDefaults(object)
+	return f.write(omittedLabel(fmt.Sprintf("%s_create", TableName(object)), omitted), object)
+}
+
+// CreateIfNotExists implements `DataAccess`, copying the first scripted result, if any, into the object.
+func (f *Fake) CreateIfNotExists(object DatabaseMapped) error {
+	return f.write(fmt.Sprintf("%s_create_if_not_exists", TableName(object)), object)
+}
+
+// CreateMany implements `DataAccess`, recording each object as an argument.
+func (f *Fake) CreateMany(objects interface{}) error {
+	_, err := f.run(fmt.Sprintf("%s_create_many", TableNameByType(reflectSliceType(objects))), "", fakeElements(objects))
+	return err
+}
+
+// Update implements `DataAccess`, copying the first scripted result, if any, into the object.
+func (f *Fake) Update(object DatabaseMapped) error {
+	_, err := f.UpdateWithResult(object)
+	return err
+}
+
+// UpdateWithResult implements `DataAccess`, copying the first scripted result, if any, into the object.
+// The result affects one row unless the expectation scripts `RowsAffected`.
+func (f *Fake) UpdateWithResult(object DatabaseMapped) (sql.Result, error) {
+	label := fmt.Sprintf("%s_update", TableName(object))
+	results, rowsAffected, err := f.runAffecting(label, []interface{}{object}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		if err = fakeAssign(object, results[0]); err != nil {
+			return nil, err
+		}
+	}
+	return f.affected(label, rowsAffected)
+}
+
+// Upsert implements `DataAccess`, copying the first scripted result, if any, into the object.
+func (f *Fake) Upsert(object DatabaseMapped) error {
+	_, omitted := getCachedColumnCollectionFromInstance(object).NotReadOnly().NotSerials().NotZeroDefaults(object)
+	return f.write(omittedLabel(fmt.Sprintf("%s_upsert", TableName(object)), omitted), object)
+}
+
+// UpsertWith implements `DataAccess`, returning the first scripted `UpsertResult`, or `UpsertResultInserted`,
+// and copying the first other scripted result, if any, into the object.
+func (f *Fake) UpsertWith(object DatabaseMapped, conflict *Conflict) (UpsertResult, error) {
+	results, err := f.run(fmt.Sprintf("%s_upsert_with", TableName(object)), "", []interface{}{object})
+	if err != nil {
+		return "", err
+	}
+	result, hasResult, hasObject := UpsertResultInserted, false, false
+	for _, scripted := range results {
+		if typed, isUpsertResult := scripted.(UpsertResult); isUpsertResult {
+			if !hasResult {
+				result, hasResult = typed, true
+			}
+			continue
+		}
+		if !hasObject {
+			if err = fakeAssign(object, scripted); err != nil {
+				return "", err
+			}
+			hasObject = true
+		}
+	}
+	return result, nil
+}
+
+// UpsertMany implements `DataAccess`, recording each object as an argument.
+// Every object is affected unless the expectation scripts `RowsAffected`.
+func (f *Fake) UpsertMany(objects interface{}) (int64, error) {
+	args := fakeElements(objects)
+	_, rowsAffected, err := f.runAffecting(fmt.Sprintf("%s_upsert_many", TableNameByType(reflectSliceType(objects))), args, int64(len(args)))
+	return rowsAffected, err
+}
+
+// Exists implements `DataAccess`, returning the first scripted result, which should be a bool, or false.
+func (f *Fake) Exists(object DatabaseMapped) (bool, error) {
+	results, err := f.run(fmt.Sprintf("%s_exists", TableName(object)), "", []interface{}{object})
+	if err != nil || len(results) == 0 {
+		return false, err
+	}
+	exists, isBool := results[0].(bool)
+	if !isBool {
+		return false, exception.Newf("fake result for `%s_exists` is not a bool", TableName(object))
+	}
+	return exists, nil
+}
+
+// Delete implements `DataAccess`.
+func (f *Fake) Delete(object DatabaseMapped) error {
+	_, err := f.DeleteWithResult(object)
+	return err
+}
+
+// DeleteWithResult implements `DataAccess`; the result affects one row unless the expectation scripts `RowsAffected`.
+func (f *Fake) DeleteWithResult(object DatabaseMapped) (sql.Result, error) {
+	label := fmt.Sprintf("%s_delete", TableName(object))
+	_, rowsAffected, err := f.runAffecting(label, []interface{}{object}, 1)
+	if err != nil {
+		return nil, err
+	}
+	return f.affected(label, rowsAffected)
+}
+
+// DeleteMany implements `DataAccess`, recording each object as an argument.
+// Every object is affected unless the expectation scripts `RowsAffected`.
+func (f *Fake) DeleteMany(objects interface{}) (int64, error) {
+	args := fakeElements(objects)
+	_, rowsAffected, err := f.runAffecting(fmt.Sprintf("%s_delete_many", TableNameByType(reflectSliceType(objects))), args, int64(len(args)))
+	return rowsAffected, err
+}
+
+// Truncate implements `DataAccess`.
+func (f *Fake) Truncate(object DatabaseMapped) error {
+	_, err := f.run(fmt.Sprintf("%s_truncate", TableName(object)), "", nil)
+	return err
+}
+
+// write runs a mapped object write, recording the object and copying the first scripted result, if any, into it.
+func (f *Fake) write(label string, object DatabaseMapped) error {
+	results, err := f.run(label, "", []interface{}{object})
+	if err != nil || len(results) == 0 {
+		return err
+	}
+	return fakeAssign(object, results[0])
+}
+
+// affected returns the result of a write that affected a number of rows, or a `NotFoundError` in strict mode if it affected none.
+func (f *Fake) affected(label string, rowsAffected int64) (sql.Result, error) {
+	if f.strict && rowsAffected == 0 {
+		return rowsAffectedResult(0), &NotFoundError{Label: label}
+	}
+	return rowsAffectedResult(rowsAffected), nil
+}
+
+// fakeElements returns the elements of a slice of objects, to record as arguments.
+func fakeElements(objects interface{}) []interface{} {
+	var elements []interface{}
+	objectsValue := reflectValue(objects)
+	for index := 0; index < objectsValue.Len(); index++ {
+		elements = append(elements, objectsValue.Index(index).Interface())
+	}
+	return elements
+}
+
+// isFakeTxLabel returns if a label is the label of a transaction begin, commit or rollback.
+func isFakeTxLabel(label string) bool {
+	return label == string(FlagTxBegin) || label == string(FlagTxCommit) || label == string(FlagTxRollback)
+}
+
+// endTx commits or rolls back the fake's transaction, if it has one.
+func (f *Fake) endTx(label string) error {
+	if f.tx == nil {
+		return nil
+	}
+	_, err := f.run(label, "", nil)
+	if err != nil {
+		return err
+	}
+	f.state.lock.Lock()
+	f.tx.done = true
+	f.state.lock.Unlock()
+	return nil
+}
+
+// run records a statement and returns the scripted results of the first expectation for its label that isn't exhausted.
+func (f *Fake) run(label, statement string, args []interface{}) ([]interface{}, error) {
+	expectation, err := f.record(label, statement, args)
+	if err != nil || expectation == nil {
+		return nil, err
+	}
+	return expectation.results, expectation.err
+}
+
+// runAffecting runs a mapped object write, returning its scripted results and rows affected, or the given rows affected
+// if the expectation doesn't script them.
+func (f *Fake) runAffecting(label string, args []interface{}, defaultRowsAffected int64) ([]interface{}, int64, error) {
+	expectation, err := f.record(label, "", args)
+	if err != nil {
+		return nil, 0, err
+	}
+	if expectation == nil {
+		return nil, defaultRowsAffected, nil
+	}
+	if expectation.err != nil {
+		return nil, 0, expectation.err
+	}
+	if expectation.hasRowsAffected {
+		return expectation.results, expectation.rowsAffected, nil
+	}
+	return expectation.results, defaultRowsAffected, nil
+}
+
+// record records a statement and returns the first expectation for its label that isn't exhausted, if any.
+func (f *Fake) record(label, statement string, args []interface{}) (*FakeExpectation, error) {
+	f.state.lock.Lock()
+	defer f.state.lock.Unlock()
+
+	recorded := FakeStatement{Label: label, Statement: statement, Args: args}
+	if f.tx != nil {
+		recorded.TxID = f.tx.id
+		if f.tx.done {
+			f.state.statements = append(f.state.statements, recorded)
+			return nil, sql.ErrTxDone
+		}
+	}
+
+	var expectation *FakeExpectation
+	for _, candidate := range f.state.expectations {
+		if candidate.label == label && (candidate.times == 0 || candidate.calls < candidate.times) {
+			expectation = candidate
+			break
+		}
+	}
+	if expectation == nil {
+		f.state.statements = append(f.state.statements, recorded)
+		return nil, nil
+	}
+
+	expectation.calls = expectation.calls + 1
+	recorded.Expected = true
+	f.state.statements = append(f.state.statements, recorded)
+	return expectation, nil
+}
+
+// Returns sets the results of the statement; objects for mapped object methods, `Out` and `OutMany`,
+// a bool for `Exists`, or a `[]interface{}` row of values for `Scan`. It returns a reference to the expectation.
+func (fe *FakeExpectation) Returns(results ...interface{}) *FakeExpectation {
+	fe.results = results
+	return fe
+}
+
+// ReturnsError sets the error the statement returns, and returns a reference to the expectation.
+func (fe *FakeExpectation) ReturnsError(err error) *FakeExpectation {
+	fe.err = err
+	return fe
+}
+
+// RowsAffected sets the number of rows the statement affects, for `Update`, `Delete`, `UpsertMany`, `DeleteMany` and their
+// `...WithResult` variants, and returns a reference to the expectation.
+// In strict mode, `Update` and `Delete` return a `NotFoundError` if they affect no rows.
+func (fe *FakeExpectation) RowsAffected(rowsAffected int64) *FakeExpectation {
+	fe.rowsAffected, fe.hasRowsAffected = rowsAffected, true
+	return fe
+}
+
+// Times sets the number of times the statement is expected to be called, and returns a reference to the expectation.
+// It defaults to at least once.
+func (fe *FakeExpectation) Times(times int) *FakeExpectation {
+	fe.times = times
+	return fe
+}
+
+// Label returns the label of the statements the expectation matches.
+func (fe *FakeExpectation) Label() string {
+	return fe.label
+}
+
+// Calls returns the number of statements that matched the expectation.
+func (fe *FakeExpectation) Calls() int {
+	return fe.calls
+}
+
+// --------------------------------------------------------------------------------
+// Fake Results
+// --------------------------------------------------------------------------------
+
+// fakeResults are the scripted results of a `Fake` query.
+type fakeResults struct {
+	label   string
+	results []interface{}
+	err     error
+	strict  bool
+}
+
+// Any implements `Results`.
+func (fr *fakeResults) Any() (bool, error) {
+	if fr.err != nil {
+		return false, fr.err
+	}
+	return len(fr.objects()) > 0, nil
+}
+
+// None implements `Results`.
+func (fr *fakeResults) None() (bool, error) {
+	if fr.err != nil {
+		return false, fr.err
+	}
+	return len(fr.objects()) == 0, nil
+}
+
+// Scan implements `Results`; the first result should be a `[]interface{}` row of values, or a single value if there is one argument.
+func (fr *fakeResults) Scan(args ...interface{}) error {
+	if fr.err != nil {
+		return fr.err
+	}
+	if len(fr.results) == 0 {
+		return fr.notFound()
+	}
+	row, isRow := fr.results[0].([]interface{})
+	if !isRow {
+		row = []interface{}{fr.results[0]}
+	}
+	if len(row) != len(args) {
+		return exception.Newf("fake result for `%s` has %d values, expected %d", fr.label, len(row), len(args))
+	}
+	for index, arg := range args {
+		if err := fakeAssign(arg, row[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Out implements `Results`.
+func (fr *fakeResults) Out(object interface{}) error {
+	if fr.err != nil {
+		return fr.err
+	}
+	objects := fr.objects()
+	if len(objects) == 0 {
+		return fr.notFound()
+	}
+	return fakeAssign(object, objects[0])
+}
+
+// OutMany implements `Results`.
+func (fr *fakeResults) OutMany(collection interface{}) error {
+	if fr.err != nil {
+		return fr.err
+	}
+	return fakeAppend(collection, fr.objects())
+}
+
+// objects returns the results with the elements of results that are slices flattened into them.
+func (fr *fakeResults) objects() []interface{} {
+	var objects []interface{}
+	for _, result := range fr.results {
+		rv := reflect.ValueOf(result)
+		if rv.Kind() != reflect.Slice {
+			objects = append(objects, result)
+			continue
+		}
+		for index := 0; index < rv.Len(); index++ {
+			objects = append(objects, rv.Index(index).Interface())
+		}
+	}
+	return objects
+}
+
+func (fr *fakeResults) notFound() error {
+	if fr.strict {
+		return &NotFoundError{Label: fr.label}
+	}
+	return nil
+}
+
+// fakeAssign sets the value a destination pointer points to from a scripted result, following pointers on the result.
+func fakeAssign(destination, result interface{}) error {
+	dv := reflect.ValueOf(destination)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return exception.Newf("fake destination `%T` is not a pointer", destination)
+	}
+	target := dv.Elem()
+	if result == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(result)
+	for !rv.Type().AssignableTo(target.Type()) && rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Type().AssignableTo(target.Type()) {
+		target.Set(rv)
+		return nil
+	}
+	if rv.Type().ConvertibleTo(target.Type()) {
+		target.Set(rv.Convert(target.Type()))
+		return nil
+	}
+	return exception.Newf("cannot assign fake result `%T` to `%T`", result, destination)
+}
+
+// fakeAppend appends scripted results, or the elements of results that are slices, to a collection.
+func fakeAppend(collection interface{}, results []interface{}) error {
+	cv := reflect.ValueOf(collection)
+	if cv.Kind() != reflect.Ptr || cv.Elem().Kind() != reflect.Slice {
+		return exception.Newf("fake destination `%T` is not a pointer to a slice", collection)
+	}
+	slice := cv.Elem()
+	for _, result := range results {
+		rv := reflect.ValueOf(result)
+		if rv.Kind() == reflect.Slice {
+			for index := 0; index < rv.Len(); index++ {
+				if err := fakeAppendValue(slice, rv.Index(index).Interface()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := fakeAppendValue(slice, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fakeAppendValue(slice reflect.Value, result interface{}) error {
+	element := reflect.New(slice.Type().Elem())
+	if err := fakeAssign(element.Interface(), result); err != nil {
+		return err
+	}
+	slice.Set(reflect.Append(slice, element.Elem()))
+	return nil
+}
@@ -0,0 +1,217 @@
+package spiffy
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+// renameBenchObj is a datamanager function used to test data access through the interface.
+func renameBenchObj(db DataAccessContext, id int, name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	var obj benchObj
+	if err = tx.Get(&obj, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	obj.Name = name
+	if err = tx.Update(&obj); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// countBenchObjs is a datamanager function that only needs the CRUD surface, so it takes connections too.
+func countBenchObjs(db DataAccess) (int, error) {
+	var objs []benchObj
+	if err := db.GetAll(&objs); err != nil {
+		return 0, err
+	}
+	return len(objs), nil
+}
+
+func TestDataAccess(t *testing.T) {
+	assert := assert.New(t)
+
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+	assert.Nil(createTable(tx))
+	assert.Nil(seedObjects(10, tx))
+
+	count, err := countBenchObjs(NewDB().WithConn(Default()).InTx(tx))
+	assert.Nil(err)
+	assert.Equal(10, count)
+
+	fake := NewFake()
+	fake.Expect("bench_object_get_all").Returns([]benchObj{{ID: 1}, {ID: 2}})
+	count, err = countBenchObjs(fake)
+	assert.Nil(err)
+	assert.Equal(2, count)
+}
+
+func TestFakeCRUD(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	fake.Expect("bench_object_get").Returns(benchObj{ID: 1, Name: "foo"})
+	fake.Expect("bench_object_update").Times(1)
+
+	assert.Nil(renameBenchObj(fake, 1, "bar"))
+	assert.Nil(fake.Verify())
+	assert.Empty(fake.Unexpected())
+
+	statements := fake.Statements()
+	assert.Len(statements, 4)
+	assert.Equal(string(FlagTxBegin), statements[0].Label)
+	assert.Equal("bench_object_get", statements[1].Label)
+	assert.Equal([]interface{}{1}, statements[1].Args)
+	assert.Equal(1, statements[1].TxID)
+	assert.Equal(string(FlagTxCommit), statements[3].Label)
+}
+
+func TestFakeScriptedErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	fake.Expect("bench_object_update").Times(1).ReturnsError(fmt.Errorf("test"))
+
+	fake.Expect("bench_object_get").Returns(&benchObj{ID: 1})
+	assert.NotNil(renameBenchObj(fake, 1, "bar"))
+	assert.Equal(string(FlagTxRollback), fake.Statements()[3].Label)
+	assert.Nil(fake.Verify())
+
+	fake.Expect("bench_object_create").Times(2)
+	assert.Nil(fake.Create(&benchObj{}))
+	assert.NotNil(fake.Verify())
+}
+
+func TestFakeTxDone(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	tx, err := fake.Begin()
+	assert.Nil(err)
+	assert.Nil(tx.Commit())
+	assert.Equal(sql.ErrTxDone, tx.Exec("select 1"))
+	assert.Equal(sql.ErrTxDone, tx.Rollback())
+	assert.Nil(fake.Commit())
+}
+
+func TestFakeQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake().WithStrict(true)
+	fake.Expect("select count(*) from bench_object").Returns([]interface{}{int64(3)})
+	fake.Expect("select * from bench_object").Returns([]benchObj{{ID: 1}, {ID: 2}})
+
+	var count int
+	assert.Nil(fake.Query("select count(*) from bench_object").Scan(&count))
+	assert.Equal(3, count)
+
+	var objs []benchObj
+	assert.Nil(fake.Query("select * from bench_object").OutMany(&objs))
+	assert.Len(objs, 2)
+
+	var obj benchObj
+	assert.Nil(fake.Query("select * from bench_object").Out(&obj))
+	assert.Equal(1, obj.ID)
+
+	none, err := fake.Query("select * from bench_object where id = $1", 3).None()
+	assert.Nil(err)
+	assert.True(none)
+	assert.True(IsNotFound(fake.Query("select * from bench_object where id = $1", 3).Out(&obj)))
+	assert.Len(fake.Unexpected(), 2)
+}
+
+func TestFakeExists(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	exists, err := fake.Exists(&benchObj{ID: 1})
+	assert.Nil(err)
+	assert.False(exists)
+
+	fake.Expect("bench_object_exists").Returns(true)
+	exists, err = fake.Exists(&benchObj{ID: 1})
+	assert.Nil(err)
+	assert.True(exists)
+}
+
+func TestFakeRecordsObjects(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	created := &defaultsObj{Name: "foo"}
+	assert.Nil(fake.Create(created))
+	assert.Nil(fake.Update(&defaultsObj{ID: 1, Name: "foo", Version: 2}))
+	assert.Nil(fake.Upsert(&defaultsObj{ID: 1, Name: "foo", CreatedUTC: time.Now().UTC(), Version: 2}))
+	assert.Nil(fake.Delete(created))
+	assert.Nil(fake.CreateMany([]benchObj{{ID: 1}, {ID: 2}}))
+
+	statements := fake.Statements()
+	assert.Len(statements, 5)
+	assert.Equal("defaults_object_create_omit_created_utc_version", statements[0].Label)
+	assert.Equal([]interface{}{created}, statements[0].Args)
+	assert.Equal("defaults_object_update", statements[1].Label)
+	assert.Equal("defaults_object_upsert", statements[2].Label)
+	assert.Equal("defaults_object_delete", statements[3].Label)
+	assert.Equal([]interface{}{created}, statements[3].Args)
+	assert.Equal("bench_object_create_many", statements[4].Label)
+	assert.Equal([]interface{}{benchObj{ID: 1}, benchObj{ID: 2}}, statements[4].Args)
+}
+
+func TestFakeRowsAffected(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	res, err := fake.UpdateWithResult(&benchObj{ID: 1})
+	assert.Nil(err)
+	rowsAffected, err := res.RowsAffected()
+	assert.Nil(err)
+	assert.Equal(int64(1), rowsAffected)
+
+	affected, err := fake.UpsertMany([]benchObj{{ID: 1}, {ID: 2}})
+	assert.Nil(err)
+	assert.Equal(int64(2), affected)
+
+	fake.Expect("bench_object_delete_many").RowsAffected(1)
+	affected, err = fake.DeleteMany([]benchObj{{ID: 1}, {ID: 2}})
+	assert.Nil(err)
+	assert.Equal(int64(1), affected)
+
+	fake.Expect("bench_object_upsert_with").Returns(UpsertResultSkipped)
+	result, err := fake.UpsertWith(&benchObj{ID: 1}, OnConflict().DoNothing())
+	assert.Nil(err)
+	assert.Equal(UpsertResultSkipped, result)
+
+	assert.Nil(fake.CopyIn([]benchObj{{ID: 3}}))
+	assert.Equal("bench_object_copy_in", fake.Statements()[4].Label)
+	assert.Equal([]interface{}{benchObj{ID: 3}}, fake.Statements()[4].Args)
+}
+
+func TestFakeStrictNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := NewFake()
+	fake.Expect("bench_object_update").RowsAffected(0)
+	fake.Expect("bench_object_delete").RowsAffected(0)
+	assert.Nil(fake.Update(&benchObj{ID: 1}))
+	assert.Nil(fake.Delete(&benchObj{ID: 1}))
+
+	fake = NewFake().WithStrict(true)
+	fake.Expect("bench_object_update").RowsAffected(0).Times(1)
+	fake.Expect("bench_object_delete").RowsAffected(0).Times(1)
+	tx, err := fake.Begin()
+	assert.Nil(err)
+	assert.True(IsNotFound(tx.Update(&benchObj{ID: 1})))
+	assert.True(IsNotFound(tx.Delete(&benchObj{ID: 1})))
+	assert.Nil(tx.Update(&benchObj{ID: 1}))
+}
@@ -0,0 +1,486 @@
+// Package fixtures loads records from yaml or json files into the database through their mapped types.
+//
+// Fixture files are keyed by table name, then by record name, then by column name:
+//
+//	users:
+//	  alice:
+//	    email: alice@example.com
+//	posts:
+//	  hello:
+//	    user_id: $users.alice.id
+//	    title: Hello World
+//
+// A value of the form `$<table>.<record>.<column>` references a column of another record, which is read after the
+// other record is created, so it can reference serial columns. Records are created in an order that satisfies their references.
+// Values that start with `$$` are written with a single leading `$` instead.
+package fixtures
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+	"github.com/blendlabs/spiffy"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// ReferencePrefix is the prefix of values that reference a column of another record.
+	ReferencePrefix = "$"
+)
+
+// New returns a new, empty set of fixtures.
+func New() *Fixtures {
+	return &Fixtures{
+		types:   map[string]reflect.Type{},
+		records: map[string]map[string]map[string]interface{}{},
+		objects: map[string]map[string]spiffy.DatabaseMapped{},
+	}
+}
+
+// Fixtures is a set of records read from fixture files, and the mapped types they're loaded into.
+type Fixtures struct {
+	types   map[string]reflect.Type
+	records map[string]map[string]map[string]interface{}
+	objects map[string]map[string]spiffy.DatabaseMapped
+}
+
+// record identifies a fixture record by its table and name.
+type record struct {
+	table string
+	name  string
+}
+
+func (r record) String() string {
+	return fmt.Sprintf("%s.%s", r.table, r.name)
+}
+
+// reference is a parsed `$<table>.<record>.<column>` value.
+type reference struct {
+	record
+	column string
+}
+
+// Register registers the mapped types that records are loaded into, by their table names, and returns a reference to the fixtures.
+func (f *Fixtures) Register(objects ...spiffy.DatabaseMapped) *Fixtures {
+	for _, object := range objects {
+		t := reflect.TypeOf(object)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		f.types[spiffy.TableName(object)] = t
+	}
+	return f
+}
+
+// ReadFile reads fixture files; files ending in `.json` are read as json, and everything else as yaml.
+func (f *Fixtures) ReadFile(paths ...string) error {
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return exception.Wrap(err)
+		}
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			err = f.ReadJSON(contents)
+		} else {
+			err = f.ReadYAML(contents)
+		}
+		if err != nil {
+			return exception.Newf("fixtures: %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// ReadYAML reads fixtures from yaml.
+func (f *Fixtures) ReadYAML(contents []byte) error {
+	var tables map[string]map[string]map[string]interface{}
+	if err := yaml.Unmarshal(contents, &tables); err != nil {
+		return exception.Wrap(err)
+	}
+	return f.add(tables)
+}
+
+// ReadJSON reads fixtures from json.
+func (f *Fixtures) ReadJSON(contents []byte) error {
+	var tables map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(contents, &tables); err != nil {
+		return exception.Wrap(err)
+	}
+	return f.add(tables)
+}
+
+// Object returns the object a record was loaded into, or nil if the record hasn't been loaded.
+func (f *Fixtures) Object(table, name string) spiffy.DatabaseMapped {
+	return f.objects[table][name]
+}
+
+// Load creates the records in an order that satisfies their references, within a transaction.
+// If a transaction isn't given, one is started and committed if all the records are created, or rolled back if not.
+// If loading fails, none of the records are kept as loaded, so a given transaction should be rolled back too.
+func (f *Fixtures) Load(conn *spiffy.Connection, optionalTx ...*sql.Tx) (err error) {
+	order, err := f.order()
+	if err != nil {
+		return
+	}
+
+	// the objects loaded before this load, restored if it fails.
+	previous := map[record]spiffy.DatabaseMapped{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for r, object := range previous {
+			if object == nil {
+				delete(f.objects[r.table], r.name)
+			} else {
+				f.objects[r.table][r.name] = object
+			}
+		}
+	}()
+
+	tx := spiffy.OptionalTx(optionalTx...)
+	if tx == nil {
+		tx, err = conn.Begin()
+		if err != nil {
+			return
+		}
+		defer func() {
+			if err == nil {
+				err = exception.Wrap(conn.Commit(tx))
+			} else {
+				err = exception.Nest(err, exception.Wrap(conn.Rollback(tx)))
+			}
+		}()
+	}
+
+	for _, r := range order {
+		var object spiffy.DatabaseMapped
+		object, err = f.populate(r)
+		if err != nil {
+			return
+		}
+		err = conn.CreateInTx(object, tx)
+		if err != nil {
+			err = exception.Newf("fixtures: creating %s: %v", r, err)
+			return
+		}
+		if f.objects[r.table] == nil {
+			f.objects[r.table] = map[string]spiffy.DatabaseMapped{}
+		}
+		if _, isStaged := previous[r]; !isStaged {
+			previous[r] = f.objects[r.table][r.name]
+		}
+		f.objects[r.table][r.name] = object
+	}
+	return
+}
+
+// add adds records read from a file.
+func (f *Fixtures) add(tables map[string]map[string]map[string]interface{}) error {
+	for table, records := range tables {
+		if _, hasType := f.types[table]; !hasType {
+			return exception.Newf("no type registered for table `%s`", table)
+		}
+		if f.records[table] == nil {
+			f.records[table] = map[string]map[string]interface{}{}
+		}
+		for name, values := range records {
+			if _, hasRecord := f.records[table][name]; hasRecord {
+				return exception.Newf("duplicate record `%s.%s`", table, name)
+			}
+			normalized := map[string]interface{}{}
+			for column, value := range values {
+				normalized[column] = normalize(value)
+			}
+			f.records[table][name] = normalized
+		}
+	}
+	return nil
+}
+
+// order returns the records sorted so that every record comes after the records it references.
+// Records without references between them are sorted by table and name, so the order is stable.
+func (f *Fixtures) order() ([]record, error) {
+	var all []record
+	dependencies := map[record][]record{}
+	for table, records := range f.records {
+		for name, values := range records {
+			r := record{table: table, name: name}
+			all = append(all, r)
+			for _, value := range values {
+				ref, isRef, err := parseReference(value)
+				if err != nil {
+					return nil, exception.Newf("fixtures: %s: %v", r, err)
+				}
+				if !isRef {
+					continue
+				}
+				if _, hasRecord := f.records[ref.table][ref.name]; !hasRecord {
+					if _, isLoaded := f.objects[ref.table][ref.name]; !isLoaded {
+						return nil, exception.Newf("fixtures: %s references missing record `%s`", r, ref.record)
+					}
+					continue
+				}
+				dependencies[r] = append(dependencies[r], ref.record)
+			}
+		}
+	}
+	sortRecords(all)
+	for _, records := range dependencies {
+		sortRecords(records)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[record]int{}
+	order := make([]record, 0, len(all))
+	var visit func(r record, path []record) error
+	visit = func(r record, path []record) error {
+		switch state[r] {
+		case visited:
+			return nil
+		case visiting:
+			var cycle []string
+			for _, p := range append(path, r) {
+				cycle = append(cycle, p.String())
+			}
+			return exception.Newf("fixtures: reference cycle %s", strings.Join(cycle, " -> "))
+		}
+		state[r] = visiting
+		for _, dependency := range dependencies[r] {
+			if err := visit(dependency, append(path, r)); err != nil {
+				return err
+			}
+		}
+		state[r] = visited
+		order = append(order, r)
+		return nil
+	}
+	for _, r := range all {
+		if err := visit(r, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func sortRecords(records []record) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].table != records[j].table {
+			return records[i].table < records[j].table
+		}
+		return records[i].name < records[j].name
+	})
+}
+
+// populate creates a new object for a record and sets its fields, resolving references to records that have been loaded.
+func (f *Fixtures) populate(r record) (spiffy.DatabaseMapped, error) {
+	t := f.types[r.table]
+	object := reflect.New(t).Interface()
+	lookup := spiffy.Columns(object).Lookup()
+
+	columns := make([]string, 0, len(f.records[r.table][r.name]))
+	for column := range f.records[r.table][r.name] {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		col, hasColumn := lookup[column]
+		if !hasColumn {
+			return nil, exception.Newf("fixtures: %s: `%s` has no column `%s`", r, t.Name(), column)
+		}
+
+		value := f.records[r.table][r.name][column]
+		ref, isRef, err := parseReference(value)
+		if err != nil {
+			return nil, exception.Newf("fixtures: %s: %v", r, err)
+		}
+		if isRef {
+			value, err = f.resolve(ref)
+			if err != nil {
+				return nil, exception.Newf("fixtures: %s: %v", r, err)
+			}
+		} else if text, isText := value.(string); isText && strings.HasPrefix(text, ReferencePrefix+ReferencePrefix) {
+			value = strings.TrimPrefix(text, ReferencePrefix)
+		}
+
+		if err = setField(object, col, value); err != nil {
+			return nil, exception.Newf("fixtures: %s.%s: %v", r, column, err)
+		}
+	}
+	return object, nil
+}
+
+// resolve returns the value of a column of a loaded record.
+func (f *Fixtures) resolve(ref reference) (interface{}, error) {
+	object, isLoaded := f.objects[ref.table][ref.name]
+	if !isLoaded {
+		return nil, exception.Newf("`%s` hasn't been loaded", ref.record)
+	}
+	col, hasColumn := spiffy.Columns(object).Lookup()[ref.column]
+	if !hasColumn {
+		return nil, exception.Newf("`%s` has no column `%s`", ref.record, ref.column)
+	}
+	return col.GetValue(object), nil
+}
+
+// parseReference parses a `$<table>.<record>.<column>` value.
+func parseReference(value interface{}) (ref reference, isRef bool, err error) {
+	text, isText := value.(string)
+	if !isText || !strings.HasPrefix(text, ReferencePrefix) || strings.HasPrefix(text, ReferencePrefix+ReferencePrefix) {
+		return
+	}
+	pieces := strings.Split(strings.TrimPrefix(text, ReferencePrefix), ".")
+	if len(pieces) != 3 {
+		err = exception.Newf("invalid reference `%s`, expected `$<table>.<record>.<column>`", text)
+		return
+	}
+	ref = reference{record: record{table: pieces[0], name: pieces[1]}, column: pieces[2]}
+	isRef = true
+	return
+}
+
+// setField sets a column's field from a value read from a fixture file, converting it to the field's type.
+func setField(object interface{}, col *spiffy.Column, value interface{}) error {
+	field := reflect.ValueOf(object).Elem().FieldByName(col.FieldName)
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if col.IsJSON {
+		contents, err := json.Marshal(value)
+		if err != nil {
+			return exception.Wrap(err)
+		}
+		target := reflect.New(field.Type())
+		if err = json.Unmarshal(contents, target.Interface()); err != nil {
+			return exception.Wrap(err)
+		}
+		field.Set(target.Elem())
+		return nil
+	}
+
+	fieldType := field.Type()
+	isPtr := fieldType.Kind() == reflect.Ptr
+	if isPtr {
+		fieldType = fieldType.Elem()
+	}
+	converted, err := convert(value, fieldType)
+	if err != nil {
+		return err
+	}
+	if isPtr {
+		ptr := reflect.New(fieldType)
+		ptr.Elem().Set(converted)
+		field.Set(ptr)
+		return nil
+	}
+	field.Set(converted)
+	return nil
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(spiffy.UUID{})
+)
+
+// convert converts a value to a type; numbers convert to other numeric types, strings to time.Time as RFC3339,
+// and strings to `spiffy.UUID` as hex, with or without dashes.
+// Numbers only convert to integer types if they are whole and in range, so `1.5` isn't silently written as `1`.
+func convert(value interface{}, t reflect.Type) (reflect.Value, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	if t == timeType && rv.Kind() == reflect.String {
+		parsed, err := time.Parse(time.RFC3339Nano, rv.String())
+		if err != nil {
+			return reflect.Value{}, exception.Wrap(err)
+		}
+		return reflect.ValueOf(parsed), nil
+	}
+	if t == uuidType && rv.Kind() == reflect.String {
+		parsed, err := parseUUID(rv.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(parsed), nil
+	}
+	if isNumber(rv.Kind()) && isInteger(t.Kind()) {
+		converted := rv.Convert(t)
+		if converted.Convert(rv.Type()).Interface() != rv.Interface() {
+			return reflect.Value{}, exception.Newf("cannot convert `%v` to `%s` without losing precision", value, t)
+		}
+		return converted, nil
+	}
+	if (isNumber(rv.Kind()) && isNumber(t.Kind())) || (rv.Kind() == t.Kind() && rv.Type().ConvertibleTo(t)) {
+		return rv.Convert(t), nil
+	}
+	if rv.Kind() == reflect.String && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return rv.Convert(t), nil
+	}
+	return reflect.Value{}, exception.Newf("cannot convert `%T` to `%s`", value, t)
+}
+
+// parseUUID parses the 16 bytes of a uuid from its hex representation, with or without dashes.
+func parseUUID(text string) (spiffy.UUID, error) {
+	parsed, err := hex.DecodeString(strings.Replace(text, "-", "", -1))
+	if err != nil || len(parsed) != 16 {
+		return nil, exception.Newf("`%s` is not a uuid", text)
+	}
+	return spiffy.UUID(parsed), nil
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return isInteger(kind) || kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// normalize converts the `map[interface{}]interface{}` values yaml reads nested maps as to `map[string]interface{}`,
+// so they can be written as json.
+func normalize(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(typed))
+		for key, inner := range typed {
+			normalized[fmt.Sprint(key)] = normalize(inner)
+		}
+		return normalized
+	case map[string]interface{}:
+		for key, inner := range typed {
+			typed[key] = normalize(inner)
+		}
+		return typed
+	case []interface{}:
+		for index, inner := range typed {
+			typed[index] = normalize(inner)
+		}
+		return typed
+	}
+	return value
+}
@@ -0,0 +1,141 @@
+package fixtures
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/spiffy"
+)
+
+type fixtureAuthor struct {
+	ID     int    `db:"id,pk,serial"`
+	Name   string `db:"name"`
+	Handle string `db:"handle"`
+}
+
+func (fa fixtureAuthor) TableName() string {
+	return "spiffy_fixture_author"
+}
+
+type fixturePost struct {
+	ID           int               `db:"id,pk,serial"`
+	AuthorID     int               `db:"author_id"`
+	Title        string            `db:"title"`
+	PublishedUTC *time.Time        `db:"published_utc"`
+	Tags         map[string]string `db:"tags,json"`
+}
+
+func (fp fixturePost) TableName() string {
+	return "spiffy_fixture_post"
+}
+
+func TestFixturesOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	f := New().Register(fixtureAuthor{}, fixturePost{})
+	assert.Nil(f.ReadJSON([]byte(`{
+		"spiffy_fixture_post": {"hello": {"author_id": "$spiffy_fixture_author.bob.id"}},
+		"spiffy_fixture_author": {"bob": {"name": "Bob"}, "alice": {"name": "Alice"}}
+	}`)))
+
+	order, err := f.order()
+	assert.Nil(err)
+	assert.Len(order, 3)
+	assert.Equal("spiffy_fixture_author.alice", order[0].String())
+	assert.Equal("spiffy_fixture_author.bob", order[1].String())
+	assert.Equal("spiffy_fixture_post.hello", order[2].String())
+}
+
+func TestFixturesConvert(t *testing.T) {
+	assert := assert.New(t)
+
+	converted, err := convert(float64(3), reflect.TypeOf(int(0)))
+	assert.Nil(err)
+	assert.Equal(3, converted.Interface())
+
+	converted, err = convert(1.5, reflect.TypeOf(float32(0)))
+	assert.Nil(err)
+	assert.Equal(float32(1.5), converted.Interface())
+
+	_, err = convert(1.5, reflect.TypeOf(int(0)))
+	assert.NotNil(err, "fractions should not be truncated")
+	_, err = convert(float64(300), reflect.TypeOf(int8(0)))
+	assert.NotNil(err, "out of range numbers should not overflow")
+	_, err = convert(float64(-1), reflect.TypeOf(uint(0)))
+	assert.NotNil(err)
+
+	converted, err = convert("6ba7b810-9dad-11d1-80b4-00c04fd430c8", reflect.TypeOf(spiffy.UUID{}))
+	assert.Nil(err)
+	assert.Equal("6ba7b810-9dad-11d1-80b4-00c04fd430c8", converted.Interface().(spiffy.UUID).ToFullString())
+	converted, err = convert("6ba7b8109dad11d180b400c04fd430c8", reflect.TypeOf(spiffy.UUID{}))
+	assert.Nil(err)
+	assert.Len(converted.Interface().(spiffy.UUID), 16)
+	_, err = convert("not-a-uuid", reflect.TypeOf(spiffy.UUID{}))
+	assert.NotNil(err)
+}
+
+func TestFixturesOrderErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	f := New().Register(fixtureAuthor{}, fixturePost{})
+	assert.NotNil(f.ReadJSON([]byte(`{"users": {"alice": {}}}`)))
+
+	assert.Nil(f.ReadJSON([]byte(`{"spiffy_fixture_post": {"hello": {"author_id": "$spiffy_fixture_author.carol.id"}}}`)))
+	_, err := f.order()
+	assert.NotNil(err)
+
+	cyclic := New().Register(fixtureAuthor{})
+	assert.Nil(cyclic.ReadJSON([]byte(`{"spiffy_fixture_author": {
+		"a": {"name": "$spiffy_fixture_author.b.name"},
+		"b": {"name": "$spiffy_fixture_author.a.name"}
+	}}`)))
+	_, err = cyclic.order()
+	assert.NotNil(err)
+}
+
+func TestFixturesLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	tx, err := spiffy.Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	assert.Nil(spiffy.Default().ExecInTx("CREATE TABLE spiffy_fixture_author (id serial primary key, name varchar(255), handle varchar(255))", tx))
+	assert.Nil(spiffy.Default().ExecInTx("CREATE TABLE spiffy_fixture_post (id serial primary key, author_id int references spiffy_fixture_author(id), title varchar(255), published_utc timestamp, tags json)", tx))
+
+	f := New().Register(fixtureAuthor{}, fixturePost{})
+	assert.Nil(f.ReadFile("testdata/fixtures.yml"))
+	assert.Nil(f.Load(spiffy.Default(), tx))
+
+	alice := f.Object("spiffy_fixture_author", "alice").(*fixtureAuthor)
+	assert.NotZero(alice.ID)
+	assert.Equal("$alice", alice.Handle)
+
+	hello := f.Object("spiffy_fixture_post", "hello").(*fixturePost)
+	assert.Equal(alice.ID, hello.AuthorID)
+	assert.NotNil(hello.PublishedUTC)
+	assert.Equal("greetings", hello.Tags["primary"])
+
+	var verify fixturePost
+	assert.Nil(spiffy.Default().GetInTx(&verify, tx, hello.ID))
+	assert.Equal(alice.ID, verify.AuthorID)
+}
+
+func TestFixturesLoadFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	tx, err := spiffy.Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	// without a post table, the authors are created and the post fails.
+	assert.Nil(spiffy.Default().ExecInTx("CREATE TABLE spiffy_fixture_author (id serial primary key, name varchar(255), handle varchar(255))", tx))
+
+	f := New().Register(fixtureAuthor{}, fixturePost{})
+	assert.Nil(f.ReadFile("testdata/fixtures.yml"))
+	assert.NotNil(f.Load(spiffy.Default(), tx))
+	assert.Nil(f.Object("spiffy_fixture_author", "alice"))
+	assert.Nil(f.Object("spiffy_fixture_post", "hello"))
+}
@@ -0,0 +1,382 @@
+// Package generator emits Go types for existing tables, with `db` tags, `TableName()` methods and,
+// optionally, `Populate` implementations, so they can be used with spiffy without writing them by hand.
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+
+	exception "github.com/blendlabs/go-exception"
+	"github.com/blendlabs/spiffy"
+)
+
+const (
+	// DefaultPackageName is the package name of generated files if one isn't set.
+	DefaultPackageName = "model"
+)
+
+// initialisms are the words that are upper cased in field and type names, ex: `user_id` becomes `UserID`.
+var initialisms = map[string]bool{
+	"api":  true,
+	"id":   true,
+	"ip":   true,
+	"json": true,
+	"http": true,
+	"sql":  true,
+	"url":  true,
+	"utc":  true,
+	"uuid": true,
+}
+
+// methodNames are the names of generated methods, which fields can't share.
+var methodNames = map[string]bool{
+	"TableName": true,
+	"Populate":  true,
+}
+
+// New returns a new generator that reads tables from a connection.
+func New(conn *spiffy.Connection) *Generator {
+	return &Generator{
+		conn:        conn,
+		packageName: DefaultPackageName,
+	}
+}
+
+// Generator emits Go types for the tables of a schema.
+type Generator struct {
+	conn        *spiffy.Connection
+	schema      string
+	packageName string
+	tables      []string
+	populate    bool
+}
+
+// WithSchema sets the schema whose tables are read, and returns a reference to the generator.
+// It defaults to the current schema.
+func (g *Generator) WithSchema(schema string) *Generator {
+	g.schema = schema
+	return g
+}
+
+// WithPackageName sets the package name of the generated file, and returns a reference to the generator.
+func (g *Generator) WithPackageName(packageName string) *Generator {
+	g.packageName = packageName
+	return g
+}
+
+// WithTables limits the generated types to the given tables, and returns a reference to the generator.
+func (g *Generator) WithTables(tables ...string) *Generator {
+	g.tables = tables
+	return g
+}
+
+// WithPopulate sets if `Populate` methods are generated, and returns a reference to the generator.
+// `Populate` scans columns in table order, so it only suits queries that select every column, like `Get` and `GetAll`.
+func (g *Generator) WithPopulate(populate bool) *Generator {
+	g.populate = populate
+	return g
+}
+
+// Generate reads the schema's tables and returns the formatted source of a Go file with a type for each.
+func (g *Generator) Generate() ([]byte, error) {
+	tables, err := g.readTables()
+	if err != nil {
+		return nil, err
+	}
+	return render(g.packageName, tables, g.populate)
+}
+
+// table is a table that a type is generated for.
+type table struct {
+	Name    string
+	Columns []column
+}
+
+// column is a column that a field is generated for.
+type column struct {
+	Name         string
+	UDTName      string
+	DataType     string
+	IsNullable   bool
+	IsPrimaryKey bool
+	IsSerial     bool
+}
+
+// readTables reads the tables, their columns and their primary keys from the schema.
+func (g *Generator) readTables() ([]table, error) {
+	introspector := g.conn.Introspect(g.schema)
+
+	names := g.tables
+	if len(names) == 0 {
+		schemaTables, err := introspector.Tables()
+		if err != nil {
+			return nil, err
+		}
+		for _, schemaTable := range schemaTables {
+			names = append(names, schemaTable.Name)
+		}
+	}
+
+	var tables []table
+	for _, name := range names {
+		schemaColumns, err := introspector.Columns(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(schemaColumns) == 0 {
+			return nil, exception.Newf("table `%s` does not exist", name)
+		}
+		pk, err := introspector.PrimaryKey(name)
+		if err != nil {
+			return nil, err
+		}
+
+		pkColumns := map[string]bool{}
+		if pk != nil {
+			for _, pkColumn := range pk.Columns {
+				pkColumns[pkColumn] = true
+			}
+		}
+
+		t := table{Name: name}
+		for _, schemaColumn := range schemaColumns {
+			t.Columns = append(t.Columns, column{
+				Name:         schemaColumn.Name,
+				UDTName:      schemaColumn.UDTName,
+				DataType:     schemaColumn.DataType,
+				IsNullable:   schemaColumn.IsNullable,
+				IsPrimaryKey: pkColumns[schemaColumn.Name],
+				IsSerial:     strings.HasPrefix(schemaColumn.Default, "nextval("),
+			})
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// render returns the formatted source of a Go file with a type for each table.
+func render(packageName string, tables []table, populate bool) ([]byte, error) {
+	imports := map[string]bool{}
+	body := bytes.NewBuffer(nil)
+	for _, t := range tables {
+		typeName := goName(t.Name)
+
+		fmt.Fprintf(body, "// %s is a row of the `%s` table.\n", typeName, t.Name)
+		fmt.Fprintf(body, "type %s struct {\n", typeName)
+		for _, col := range t.Columns {
+			goType, importPath := fieldType(col)
+			if len(importPath) > 0 {
+				imports[importPath] = true
+			}
+			fmt.Fprintf(body, "\t%s %s `db:\"%s\"`\n", fieldName(col), goType, strings.Join(tagArgs(col), ","))
+		}
+		fmt.Fprint(body, "}\n\n")
+
+		fmt.Fprintf(body, "// TableName returns the mapped table name.\n")
+		fmt.Fprintf(body, "func (%s %s) TableName() string {\n\treturn %q\n}\n\n", receiverName(typeName), typeName, t.Name)
+
+		if populate {
+			imports["database/sql"] = true
+			if renderPopulate(body, typeName, t) {
+				imports["encoding/json"] = true
+			}
+		}
+	}
+
+	source := bytes.NewBuffer(nil)
+	fmt.Fprint(source, "// Code generated by spiffy-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(source, "package %s\n\n", packageName)
+	if len(imports) > 0 {
+		// standard library imports are grouped ahead of the others.
+		var standardPaths, otherPaths []string
+		for importPath := range imports {
+			if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+				otherPaths = append(otherPaths, importPath)
+			} else {
+				standardPaths = append(standardPaths, importPath)
+			}
+		}
+		sort.Strings(standardPaths)
+		sort.Strings(otherPaths)
+		fmt.Fprint(source, "import (\n")
+		for _, importPath := range standardPaths {
+			fmt.Fprintf(source, "\t%q\n", importPath)
+		}
+		if len(standardPaths) > 0 && len(otherPaths) > 0 {
+			fmt.Fprint(source, "\n")
+		}
+		for _, importPath := range otherPaths {
+			fmt.Fprintf(source, "\t%q\n", importPath)
+		}
+		fmt.Fprint(source, ")\n\n")
+	}
+	source.Write(body.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return formatted, nil
+}
+
+// renderPopulate writes a `Populate` method that scans the columns in order, and returns if it unmarshals json.
+func renderPopulate(body *bytes.Buffer, typeName string, t table) (usesJSON bool) {
+	receiver := receiverName(typeName)
+
+	var jsonColumns []column
+	var destinations []string
+	for _, col := range t.Columns {
+		if isJSON(col) {
+			jsonColumns = append(jsonColumns, col)
+			destinations = append(destinations, "&"+jsonVariableName(col))
+			continue
+		}
+		destinations = append(destinations, fmt.Sprintf("&%s.%s", receiver, fieldName(col)))
+	}
+
+	fmt.Fprintf(body, "// Populate scans a row with every column of the `%s` table, in order.\n", t.Name)
+	fmt.Fprintf(body, "func (%s *%s) Populate(rows *sql.Rows) error {\n", receiver, typeName)
+	if len(jsonColumns) == 0 {
+		fmt.Fprintf(body, "\treturn rows.Scan(%s)\n}\n\n", strings.Join(destinations, ", "))
+		return false
+	}
+
+	for _, col := range jsonColumns {
+		fmt.Fprintf(body, "\tvar %s []byte\n", jsonVariableName(col))
+	}
+	fmt.Fprintf(body, "\tif err := rows.Scan(%s); err != nil {\n\t\treturn err\n\t}\n", strings.Join(destinations, ", "))
+	for _, col := range jsonColumns {
+		fmt.Fprintf(body, "\tif len(%s) > 0 {\n", jsonVariableName(col))
+		fmt.Fprintf(body, "\t\tif err := json.Unmarshal(%s, &%s.%s); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n", jsonVariableName(col), receiver, fieldName(col))
+	}
+	fmt.Fprint(body, "\treturn nil\n}\n\n")
+	return true
+}
+
+// tagArgs returns the name and flags of a column's `db` tag.
+func tagArgs(col column) []string {
+	args := []string{col.Name}
+	if col.IsPrimaryKey {
+		args = append(args, "pk")
+	}
+	if col.IsSerial {
+		args = append(args, "serial")
+	}
+	// columns whose field types can be nil are flagged instead of made pointers.
+	if col.IsNullable && isNilable(col) {
+		args = append(args, "nullable")
+	}
+	if isJSON(col) {
+		args = append(args, "json")
+	}
+	return args
+}
+
+// fieldType returns the Go type of a column's field, and the import path it needs, if any.
+// Nullable columns are pointers, unless the type can already be nil.
+func fieldType(col column) (string, string) {
+	goType, importPath := baseFieldType(col)
+	if col.IsNullable && !isNilable(col) {
+		return "*" + goType, importPath
+	}
+	return goType, importPath
+}
+
+// baseFieldType returns the Go type of a column's field, regardless of nullability.
+func baseFieldType(col column) (string, string) {
+	switch col.UDTName {
+	case "bool":
+		return "bool", ""
+	case "int2":
+		return "int16", ""
+	case "int4":
+		return "int", ""
+	case "int8":
+		return "int64", ""
+	case "float4":
+		return "float32", ""
+	case "float8", "numeric":
+		return "float64", ""
+	case "timestamp", "timestamptz", "date":
+		return "time.Time", "time"
+	case "json", "jsonb":
+		return "interface{}", ""
+	case "bytea":
+		return "[]byte", ""
+	case "_text", "_varchar", "_bpchar", "_uuid":
+		return "pq.StringArray", "github.com/lib/pq"
+	case "_int2", "_int4", "_int8":
+		return "pq.Int64Array", "github.com/lib/pq"
+	case "_float4", "_float8", "_numeric":
+		return "pq.Float64Array", "github.com/lib/pq"
+	case "_bool":
+		return "pq.BoolArray", "github.com/lib/pq"
+	}
+	if col.DataType == "ARRAY" {
+		return "[]byte", ""
+	}
+	// text, enums, uuids and the like are read as strings.
+	return "string", ""
+}
+
+// isJSON returns if a column's field is serialized to and from json.
+func isJSON(col column) bool {
+	return col.UDTName == "json" || col.UDTName == "jsonb"
+}
+
+// isNilable returns if a column's field type can be nil, ex: slices and interfaces.
+func isNilable(col column) bool {
+	goType, _ := baseFieldType(col)
+	return strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "pq.") || goType == "interface{}"
+}
+
+// goName returns the exported Go name for a table or column name, ex: `created_utc` becomes `CreatedUTC`.
+func goName(name string) string {
+	var output string
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == ' ' || r == '-' || r == '.' }) {
+		lower := strings.ToLower(word)
+		if initialisms[lower] {
+			output = output + strings.ToUpper(lower)
+			continue
+		}
+		output = output + strings.ToUpper(lower[:1]) + lower[1:]
+	}
+	if len(output) == 0 || (output[0] >= '0' && output[0] <= '9') {
+		output = "X" + output
+	}
+	return output
+}
+
+// fieldName returns the name of a column's field, which is suffixed with `Column` if it would collide with a generated method.
+func fieldName(col column) string {
+	name := goName(col.Name)
+	if methodNames[name] {
+		return name + "Column"
+	}
+	return name
+}
+
+// receiverName returns the receiver name for a type's methods, ex: `UserSession` becomes `us`.
+func receiverName(typeName string) string {
+	var receiver string
+	for _, r := range typeName {
+		if r >= 'A' && r <= 'Z' {
+			receiver = receiver + string(r)
+		}
+	}
+	receiver = strings.ToLower(receiver)
+	if len(receiver) == 0 || token.Lookup(receiver).IsKeyword() {
+		return "obj"
+	}
+	return receiver
+}
+
+// jsonVariableName returns the name of the variable a json column is scanned into by `Populate`.
+func jsonVariableName(col column) string {
+	name := fieldName(col)
+	return strings.ToLower(name[:1]) + name[1:] + "JSON"
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/spiffy"
+)
+
+func TestGoName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("UserID", goName("user_id"))
+	assert.Equal("CreatedUTC", goName("created_utc"))
+	assert.Equal("APIKey", goName("API_KEY"))
+	assert.Equal("X2fa", goName("2fa"))
+	assert.Equal("TableNameColumn", fieldName(column{Name: "table_name"}))
+	assert.Equal("us", receiverName("UserSession"))
+	assert.Equal("obj", receiverName("IF"))
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	source, err := render("model", []table{{
+		Name: "document",
+		Columns: []column{
+			{Name: "id", UDTName: "int4", DataType: "integer", IsPrimaryKey: true, IsSerial: true},
+			{Name: "owner_id", UDTName: "int8", DataType: "bigint"},
+			{Name: "title", UDTName: "varchar", DataType: "character varying", IsNullable: true},
+			{Name: "tags", UDTName: "_text", DataType: "ARRAY", IsNullable: true},
+			{Name: "metadata", UDTName: "jsonb", DataType: "jsonb"},
+			{Name: "created_utc", UDTName: "timestamp", DataType: "timestamp without time zone"},
+		},
+	}}, true)
+	assert.Nil(err)
+	assert.Equal(`// Code generated by spiffy-gen. DO NOT EDIT.
+
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Document is a row of the `+"`document`"+` table.
+type Document struct {
+	ID         int            `+"`db:\"id,pk,serial\"`"+`
+	OwnerID    int64          `+"`db:\"owner_id\"`"+`
+	Title      *string        `+"`db:\"title\"`"+`
+	Tags       pq.StringArray `+"`db:\"tags,nullable\"`"+`
+	Metadata   interface{}    `+"`db:\"metadata,json\"`"+`
+	CreatedUTC time.Time      `+"`db:\"created_utc\"`"+`
+}
+
+// TableName returns the mapped table name.
+func (d Document) TableName() string {
+	return "document"
+}
+
+// Populate scans a row with every column of the `+"`document`"+` table, in order.
+func (d *Document) Populate(rows *sql.Rows) error {
+	var metadataJSON []byte
+	if err := rows.Scan(&d.ID, &d.OwnerID, &d.Title, &d.Tags, &metadataJSON, &d.CreatedUTC); err != nil {
+		return err
+	}
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &d.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+`, string(source))
+}
+
+func TestGeneratorGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	schema := fmt.Sprintf("generator_%s", spiffy.UUIDv4().ToShortString())
+	assert.Nil(spiffy.Default().Exec(fmt.Sprintf("CREATE SCHEMA %s", schema)))
+	defer spiffy.Default().Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+	assert.Nil(spiffy.Default().Exec(fmt.Sprintf("CREATE TABLE %s.user_session (session_id varchar(64) not null, user_id bigint not null, expires_utc timestamptz, CONSTRAINT pk_user_session PRIMARY KEY (session_id))", schema)))
+
+	source, err := New(spiffy.Default()).WithSchema(schema).WithPackageName("sessions").Generate()
+	assert.Nil(err)
+	assert.Contains(string(source), "package sessions")
+	assert.Contains(string(source), "type UserSession struct {")
+	assert.Contains(string(source), "SessionID  string     `db:\"session_id,pk\"`")
+	assert.Contains(string(source), "ExpiresUTC *time.Time `db:\"expires_utc\"`")
+	assert.Contains(string(source), "func (us UserSession) TableName() string {")
+	assert.NotContains(string(source), "Populate")
+
+	_, err = New(spiffy.Default()).WithSchema(schema).WithTables("missing").Generate()
+	assert.NotNil(err)
+}
@@ -26,3 +26,61 @@ type Populatable interface {
 
 // RowsConsumer is the function signature that is called from within Each().
 type RowsConsumer func(r *sql.Rows) error
+
+// CopyInIterator is the function signature that is called from within CopyInFrom().
+// It should return the next object to copy, or `nil` when there are no more objects.
+type CopyInIterator func() (DatabaseMapped, error)
+
+// Results is the read surface of a query's results; `*Query` implements it.
+type Results interface {
+	Any() (bool, error)
+	None() (bool, error)
+	Scan(args ...interface{}) error
+	Out(object interface{}) error
+	OutMany(collection interface{}) error
+}
+
+// DataAccess is the CRUD surface of a connection or database context.
+// `*Connection` and `*DB` implement it against a database, and `*Fake` implements it in memory, so datamanager functions
+// that take a `DataAccess` can be unit tested without a database.
+type DataAccess interface {
+	Exec(statement string, args ...interface{}) error
+	Get(object DatabaseMapped, ids ...interface{}) error
+	GetAll(collection interface{}) error
+	Create(object DatabaseMapped) error
+	CreateIfNotExists(object DatabaseMapped) error
+	CreateMany(objects interface{}) error
+	CopyIn(collection interface{}) error
+	Update(object DatabaseMapped) error
+	UpdateWithResult(object DatabaseMapped) (sql.Result, error)
+	Upsert(object DatabaseMapped) error
+	UpsertWith(object DatabaseMapped, conflict *Conflict) (UpsertResult, error)
+	UpsertMany(objects interface{}) (int64, error)
+	Exists(object DatabaseMapped) (bool, error)
+	Delete(object DatabaseMapped) error
+	DeleteWithResult(object DatabaseMapped) (sql.Result, error)
+	DeleteMany(objects interface{}) (int64, error)
+	Truncate(object DatabaseMapped) error
+}
+
+// DataAccessContext is a `DataAccess` that also runs queries and transactions, like a database context.
+// `*DB` and `*Fake` implement it. `*Connection` can't, without breaking its existing callers: its `Query` returns `*Query`
+// rather than `Results`, and its `Begin` returns the `*sql.Tx` that the `...InTx` methods take; use `Connection.DB()`
+// to get a `*DB` for a connection instead.
+type DataAccessContext interface {
+	DataAccess
+	Query(statement string, args ...interface{}) Results
+
+	// Begin returns a copy of the context bound to a new transaction.
+	Begin() (DataAccessContext, error)
+	Commit() error
+	Rollback() error
+}
+
+var (
+	_ DataAccess        = (*Connection)(nil)
+	_ DataAccess        = (*DB)(nil)
+	_ DataAccessContext = (*DB)(nil)
+	_ DataAccessContext = (*Fake)(nil)
+	_ Results           = (*Query)(nil)
+)
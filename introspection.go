@@ -0,0 +1,375 @@
+package spiffy
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// SchemaConstraintType is the type of a table constraint.
+type SchemaConstraintType string
+
+const (
+	// SchemaConstraintPrimaryKey is a primary key constraint.
+	SchemaConstraintPrimaryKey SchemaConstraintType = "primary_key"
+	// SchemaConstraintForeignKey is a foreign key constraint.
+	SchemaConstraintForeignKey SchemaConstraintType = "foreign_key"
+	// SchemaConstraintUnique is a unique constraint.
+	SchemaConstraintUnique SchemaConstraintType = "unique"
+	// SchemaConstraintCheck is a check constraint.
+	SchemaConstraintCheck SchemaConstraintType = "check"
+	// SchemaConstraintExclusion is an exclusion constraint.
+	SchemaConstraintExclusion SchemaConstraintType = "exclusion"
+	// SchemaConstraintTrigger is a constraint trigger.
+	SchemaConstraintTrigger SchemaConstraintType = "trigger"
+)
+
+// constraintTypes maps `pg_constraint.contype` to constraint types.
+var constraintTypes = map[string]SchemaConstraintType{
+	"p": SchemaConstraintPrimaryKey,
+	"f": SchemaConstraintForeignKey,
+	"u": SchemaConstraintUnique,
+	"c": SchemaConstraintCheck,
+	"x": SchemaConstraintExclusion,
+	"t": SchemaConstraintTrigger,
+}
+
+// foreignKeyActions maps `pg_constraint.confupdtype` and `confdeltype` to the actions' names.
+var foreignKeyActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// SchemaTable is a table.
+type SchemaTable struct {
+	Schema string
+	Name   string
+}
+
+// SchemaColumn is a column of a table.
+type SchemaColumn struct {
+	Table           string
+	Name            string
+	OrdinalPosition int
+	// DataType is the column's data type as `information_schema.columns` names it, ex: `character varying`, or `USER-DEFINED` for enums.
+	DataType string
+	// UDTName is the name of the column's underlying type, ex: `varchar`, or the name of an enum.
+	UDTName    string
+	IsNullable bool
+	// Default is the column's default expression, or empty if it doesn't have one.
+	Default string
+}
+
+// SchemaPrimaryKey is the primary key of a table.
+type SchemaPrimaryKey struct {
+	Table   string
+	Name    string
+	Columns []string
+}
+
+// SchemaForeignKey is a foreign key from a table's columns to another table's columns.
+type SchemaForeignKey struct {
+	Table             string
+	Name              string
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+	// OnUpdate and OnDelete are the foreign key's actions, ex: `NO ACTION` or `CASCADE`.
+	OnUpdate string
+	OnDelete string
+}
+
+// SchemaIndex is an index on a table.
+type SchemaIndex struct {
+	Table string
+	Name  string
+	// Columns are the index's columns; expressions are left out.
+	Columns   []string
+	IsUnique  bool
+	IsPrimary bool
+	// Definition is the `CREATE INDEX` statement for the index.
+	Definition string
+}
+
+// SchemaConstraint is a constraint on a table.
+type SchemaConstraint struct {
+	Table   string
+	Name    string
+	Type    SchemaConstraintType
+	Columns []string
+	// Definition is the constraint's definition, ex: `CHECK ((amount > 0))`.
+	Definition string
+}
+
+// SchemaEnum is an enum type.
+type SchemaEnum struct {
+	Name   string
+	Values []string
+}
+
+// SchemaView is a view or materialized view.
+type SchemaView struct {
+	Name           string
+	IsMaterialized bool
+	// Definition is the view's `SELECT` statement.
+	Definition string
+}
+
+// SchemaSequence is a sequence.
+type SchemaSequence struct {
+	Name      string
+	DataType  string
+	Start     int64
+	Min       int64
+	Max       int64
+	Increment int64
+	Cycle     bool
+}
+
+// Introspect returns an introspector for a schema, or for the current schema, the first schema in the search path, if the name is empty.
+func (dbc *Connection) Introspect(schemaName string) *Introspector {
+	return dbc.IntrospectInTx(schemaName, nil)
+}
+
+// IntrospectInTx returns an introspector for a schema that queries within a transaction.
+func (dbc *Connection) IntrospectInTx(schemaName string, tx *sql.Tx) *Introspector {
+	return &Introspector{conn: dbc, tx: tx, schema: schemaName}
+}
+
+// Introspector describes the tables, columns, keys, indexes, constraints, enums, views and sequences of a schema.
+// Table names are matched exactly, so unquoted identifiers should be passed in lower case.
+type Introspector struct {
+	conn   *Connection
+	tx     *sql.Tx
+	schema string
+}
+
+// Tables returns the schema's tables, ordered by name.
+func (i *Introspector) Tables() ([]SchemaTable, error) {
+	var tables []SchemaTable
+	err := i.query(`SELECT n.nspname, c.relname
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND c.relkind IN ('r', 'p')
+ORDER BY c.relname`, func(rows *sql.Rows) error {
+		var table SchemaTable
+		if err := rows.Scan(&table.Schema, &table.Name); err != nil {
+			return err
+		}
+		tables = append(tables, table)
+		return nil
+	})
+	return tables, err
+}
+
+// TableExists returns if a table exists in the schema.
+func (i *Introspector) TableExists(tableName string) (bool, error) {
+	return i.conn.QueryInTx(`SELECT 1
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND c.relkind IN ('r', 'p') AND c.relname = $2`, i.tx, i.schema, tableName).Any()
+}
+
+// Columns returns a table's columns, in order, or none if the table doesn't exist.
+func (i *Introspector) Columns(tableName string) ([]SchemaColumn, error) {
+	var columns []SchemaColumn
+	err := i.query(`SELECT c.table_name, c.column_name, c.ordinal_position, c.data_type, c.udt_name, c.is_nullable = 'YES', coalesce(c.column_default, '')
+FROM information_schema.columns c
+WHERE c.table_schema = coalesce(nullif($1, ''), current_schema()) AND c.table_name = $2
+ORDER BY c.ordinal_position`, func(rows *sql.Rows) error {
+		var column SchemaColumn
+		if err := rows.Scan(&column.Table, &column.Name, &column.OrdinalPosition, &column.DataType, &column.UDTName, &column.IsNullable, &column.Default); err != nil {
+			return err
+		}
+		columns = append(columns, column)
+		return nil
+	}, tableName)
+	return columns, err
+}
+
+// ColumnExists returns if a column exists on a table in the schema.
+func (i *Introspector) ColumnExists(tableName, columnName string) (bool, error) {
+	return i.conn.QueryInTx(`SELECT 1
+FROM information_schema.columns c
+WHERE c.table_schema = coalesce(nullif($1, ''), current_schema()) AND c.table_name = $2 AND c.column_name = $3`, i.tx, i.schema, tableName, columnName).Any()
+}
+
+// PrimaryKey returns a table's primary key, or nil if it doesn't have one.
+func (i *Introspector) PrimaryKey(tableName string) (*SchemaPrimaryKey, error) {
+	constraints, err := i.Constraints(tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, constraint := range constraints {
+		if constraint.Type == SchemaConstraintPrimaryKey {
+			return &SchemaPrimaryKey{Table: constraint.Table, Name: constraint.Name, Columns: constraint.Columns}, nil
+		}
+	}
+	return nil, nil
+}
+
+// ForeignKeys returns the foreign keys from a table's columns, ordered by name.
+func (i *Introspector) ForeignKeys(tableName string) ([]SchemaForeignKey, error) {
+	var foreignKeys []SchemaForeignKey
+	err := i.query(`SELECT t.relname, con.conname,
+	array(SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord) JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+	rn.nspname, rt.relname,
+	array(SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord) JOIN pg_catalog.pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.ord),
+	con.confupdtype::text, con.confdeltype::text
+FROM pg_catalog.pg_constraint con
+JOIN pg_catalog.pg_class t ON t.oid = con.conrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_catalog.pg_class rt ON rt.oid = con.confrelid
+JOIN pg_catalog.pg_namespace rn ON rn.oid = rt.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND t.relname = $2 AND con.contype = 'f'
+ORDER BY con.conname`, func(rows *sql.Rows) error {
+		var foreignKey SchemaForeignKey
+		var columns, referencedColumns pq.StringArray
+		var onUpdate, onDelete string
+		if err := rows.Scan(&foreignKey.Table, &foreignKey.Name, &columns, &foreignKey.ReferencedSchema, &foreignKey.ReferencedTable, &referencedColumns, &onUpdate, &onDelete); err != nil {
+			return err
+		}
+		foreignKey.Columns = []string(columns)
+		foreignKey.ReferencedColumns = []string(referencedColumns)
+		foreignKey.OnUpdate = foreignKeyActions[onUpdate]
+		foreignKey.OnDelete = foreignKeyActions[onDelete]
+		foreignKeys = append(foreignKeys, foreignKey)
+		return nil
+	}, tableName)
+	return foreignKeys, err
+}
+
+// Indexes returns the indexes on a table, including those that back primary keys and unique constraints, ordered by name.
+func (i *Introspector) Indexes(tableName string) ([]SchemaIndex, error) {
+	var indexes []SchemaIndex
+	err := i.query(`SELECT t.relname, ic.relname,
+	array(SELECT a.attname FROM unnest(ix.indkey::int2[]) WITH ORDINALITY k(attnum, ord) JOIN pg_catalog.pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum ORDER BY k.ord),
+	ix.indisunique, ix.indisprimary, pg_catalog.pg_get_indexdef(ix.indexrelid)
+FROM pg_catalog.pg_index ix
+JOIN pg_catalog.pg_class t ON t.oid = ix.indrelid
+JOIN pg_catalog.pg_class ic ON ic.oid = ix.indexrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND t.relname = $2
+ORDER BY ic.relname`, func(rows *sql.Rows) error {
+		var index SchemaIndex
+		var columns pq.StringArray
+		if err := rows.Scan(&index.Table, &index.Name, &columns, &index.IsUnique, &index.IsPrimary, &index.Definition); err != nil {
+			return err
+		}
+		index.Columns = []string(columns)
+		indexes = append(indexes, index)
+		return nil
+	}, tableName)
+	return indexes, err
+}
+
+// IndexExists returns if an index exists on a table in the schema.
+func (i *Introspector) IndexExists(tableName, indexName string) (bool, error) {
+	return i.conn.QueryInTx(`SELECT 1
+FROM pg_catalog.pg_index ix
+JOIN pg_catalog.pg_class t ON t.oid = ix.indrelid
+JOIN pg_catalog.pg_class ic ON ic.oid = ix.indexrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND t.relname = $2 AND ic.relname = $3`, i.tx, i.schema, tableName, indexName).Any()
+}
+
+// Constraints returns the constraints on a table, ordered by name.
+func (i *Introspector) Constraints(tableName string) ([]SchemaConstraint, error) {
+	var constraints []SchemaConstraint
+	err := i.query(`SELECT t.relname, con.conname, con.contype::text,
+	array(SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord) JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+	pg_catalog.pg_get_constraintdef(con.oid)
+FROM pg_catalog.pg_constraint con
+JOIN pg_catalog.pg_class t ON t.oid = con.conrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND t.relname = $2
+ORDER BY con.conname`, func(rows *sql.Rows) error {
+		var constraint SchemaConstraint
+		var constraintType string
+		var columns pq.StringArray
+		if err := rows.Scan(&constraint.Table, &constraint.Name, &constraintType, &columns, &constraint.Definition); err != nil {
+			return err
+		}
+		constraint.Type = constraintTypes[constraintType]
+		constraint.Columns = []string(columns)
+		constraints = append(constraints, constraint)
+		return nil
+	}, tableName)
+	return constraints, err
+}
+
+// ConstraintExists returns if a constraint exists in the schema.
+func (i *Introspector) ConstraintExists(constraintName string) (bool, error) {
+	return i.conn.QueryInTx(`SELECT 1
+FROM pg_catalog.pg_constraint con
+JOIN pg_catalog.pg_namespace n ON n.oid = con.connamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND con.conname = $2`, i.tx, i.schema, constraintName).Any()
+}
+
+// Enums returns the schema's enum types and their values in order, ordered by name.
+func (i *Introspector) Enums() ([]SchemaEnum, error) {
+	var enums []SchemaEnum
+	err := i.query(`SELECT t.typname, array(SELECT e.enumlabel FROM pg_catalog.pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder)
+FROM pg_catalog.pg_type t
+JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND t.typtype = 'e'
+ORDER BY t.typname`, func(rows *sql.Rows) error {
+		var enum SchemaEnum
+		var values pq.StringArray
+		if err := rows.Scan(&enum.Name, &values); err != nil {
+			return err
+		}
+		enum.Values = []string(values)
+		enums = append(enums, enum)
+		return nil
+	})
+	return enums, err
+}
+
+// Views returns the schema's views and materialized views, ordered by name.
+func (i *Introspector) Views() ([]SchemaView, error) {
+	var views []SchemaView
+	err := i.query(`SELECT c.relname, c.relkind = 'm', pg_catalog.pg_get_viewdef(c.oid)
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND c.relkind IN ('v', 'm')
+ORDER BY c.relname`, func(rows *sql.Rows) error {
+		var view SchemaView
+		if err := rows.Scan(&view.Name, &view.IsMaterialized, &view.Definition); err != nil {
+			return err
+		}
+		views = append(views, view)
+		return nil
+	})
+	return views, err
+}
+
+// Sequences returns the schema's sequences, ordered by name.
+// Sequences are read from `information_schema.sequences`, rather than `pg_sequences`, which needs postgres 10,
+// so sequences the user has no privileges on aren't returned, and before postgres 10 the data type is always `bigint`.
+func (i *Introspector) Sequences() ([]SchemaSequence, error) {
+	var sequences []SchemaSequence
+	err := i.query(`SELECT c.relname, s.data_type, s.start_value::bigint, s.minimum_value::bigint, s.maximum_value::bigint, s.increment::bigint, s.cycle_option = 'YES'
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+JOIN information_schema.sequences s ON s.sequence_schema = n.nspname AND s.sequence_name = c.relname
+WHERE n.nspname = coalesce(nullif($1, ''), current_schema()) AND c.relkind = 'S'
+ORDER BY c.relname`, func(rows *sql.Rows) error {
+		var sequence SchemaSequence
+		if err := rows.Scan(&sequence.Name, &sequence.DataType, &sequence.Start, &sequence.Min, &sequence.Max, &sequence.Increment, &sequence.Cycle); err != nil {
+			return err
+		}
+		sequences = append(sequences, sequence)
+		return nil
+	})
+	return sequences, err
+}
+
+// query runs a catalog query whose first argument is the schema name.
+func (i *Introspector) query(statement string, consumer RowsConsumer, args ...interface{}) error {
+	return i.conn.QueryInTx(statement, i.tx, append([]interface{}{i.schema}, args...)...).Each(consumer)
+}
@@ -0,0 +1,98 @@
+package spiffy
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestIntrospector(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	statements := []string{
+		"CREATE SCHEMA introspection_test",
+		"CREATE TYPE introspection_test.status AS ENUM ('pending', 'done')",
+		"CREATE TABLE introspection_test.owner (id serial not null, email varchar(255) not null, CONSTRAINT pk_owner PRIMARY KEY (id), CONSTRAINT uk_owner_email UNIQUE (email))",
+		"CREATE TABLE introspection_test.task (id serial not null, owner_id int not null, status introspection_test.status, amount int default 0, CONSTRAINT pk_task PRIMARY KEY (id), CONSTRAINT fk_task_owner FOREIGN KEY (owner_id) REFERENCES introspection_test.owner (id) ON DELETE CASCADE, CONSTRAINT ck_task_amount CHECK (amount >= 0))",
+		"CREATE INDEX ix_task_owner_status ON introspection_test.task (owner_id, status)",
+		"CREATE VIEW introspection_test.pending_task AS SELECT id FROM introspection_test.task WHERE status = 'pending'",
+		"CREATE SEQUENCE introspection_test.ticket START 100 INCREMENT 10",
+	}
+	for _, statement := range statements {
+		assert.Nil(Default().ExecInTx(statement, tx))
+	}
+
+	introspector := Default().IntrospectInTx("introspection_test", tx)
+
+	tables, err := introspector.Tables()
+	assert.Nil(err)
+	assert.Equal([]SchemaTable{{Schema: "introspection_test", Name: "owner"}, {Schema: "introspection_test", Name: "task"}}, tables)
+
+	exists, err := introspector.TableExists("task")
+	assert.Nil(err)
+	assert.True(exists)
+	exists, err = Default().IntrospectInTx("", tx).TableExists("task")
+	assert.Nil(err)
+	assert.False(exists, "the current schema should be public")
+
+	columns, err := introspector.Columns("task")
+	assert.Nil(err)
+	assert.Len(columns, 4)
+	assert.Equal(SchemaColumn{Table: "task", Name: "status", OrdinalPosition: 3, DataType: "USER-DEFINED", UDTName: "status", IsNullable: true}, columns[2])
+	assert.Equal("0", columns[3].Default)
+	assert.False(columns[1].IsNullable)
+
+	pk, err := introspector.PrimaryKey("task")
+	assert.Nil(err)
+	assert.Equal(&SchemaPrimaryKey{Table: "task", Name: "pk_task", Columns: []string{"id"}}, pk)
+
+	foreignKeys, err := introspector.ForeignKeys("task")
+	assert.Nil(err)
+	assert.Equal([]SchemaForeignKey{{
+		Table:             "task",
+		Name:              "fk_task_owner",
+		Columns:           []string{"owner_id"},
+		ReferencedSchema:  "introspection_test",
+		ReferencedTable:   "owner",
+		ReferencedColumns: []string{"id"},
+		OnUpdate:          "NO ACTION",
+		OnDelete:          "CASCADE",
+	}}, foreignKeys)
+
+	indexes, err := introspector.Indexes("task")
+	assert.Nil(err)
+	assert.Len(indexes, 2)
+	assert.Equal("ix_task_owner_status", indexes[0].Name)
+	assert.Equal([]string{"owner_id", "status"}, indexes[0].Columns)
+	assert.False(indexes[0].IsUnique)
+	assert.True(indexes[1].IsPrimary)
+
+	constraints, err := introspector.Constraints("owner")
+	assert.Nil(err)
+	assert.Len(constraints, 2)
+	assert.Equal(SchemaConstraint{Table: "owner", Name: "uk_owner_email", Type: SchemaConstraintUnique, Columns: []string{"email"}, Definition: "UNIQUE (email)"}, constraints[1])
+
+	exists, err = introspector.ConstraintExists("ck_task_amount")
+	assert.Nil(err)
+	assert.True(exists)
+
+	enums, err := introspector.Enums()
+	assert.Nil(err)
+	assert.Equal([]SchemaEnum{{Name: "status", Values: []string{"pending", "done"}}}, enums)
+
+	views, err := introspector.Views()
+	assert.Nil(err)
+	assert.Len(views, 1)
+	assert.Equal("pending_task", views[0].Name)
+	assert.False(views[0].IsMaterialized)
+
+	sequences, err := introspector.Sequences()
+	assert.Nil(err)
+	assert.Len(sequences, 3)
+	assert.Equal("ticket", sequences[2].Name)
+	assert.Equal(int64(100), sequences[2].Start)
+	assert.Equal(int64(10), sequences[2].Increment)
+}
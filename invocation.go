@@ -6,14 +6,19 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	exception "github.com/blendlabs/go-exception"
 	logger "github.com/blendlabs/go-logger"
+	"github.com/lib/pq"
 )
 
 const (
 	connectionErrorMessage = "invocation context; db connection is nil"
+
+	// maxStatementParameters is the maximum number of bind parameters postgres allows in a single statement.
+	maxStatementParameters = 65535
 )
 
 // Invocation is a specific operation against a context.
@@ -22,8 +27,14 @@ type Invocation struct {
 	ctx            context.Context
 	tx             *sql.Tx
 	fireEvents     bool
+	strict         bool
 	statementLabel string
 	err            error
+
+	details   eventDetails
+	tableName string
+	span      Span
+	spanCtx   context.Context
 }
 
 // WithCtx sets the ctx and returns a reference to the invocation.
@@ -48,6 +59,19 @@ func (i *Invocation) WithFireEvents(flag bool) *Invocation {
 	return i
 }
 
+// Strict returns if strict mode is enabled.
+// In strict mode `Update` and `Delete` return a `NotFoundError` if no row matches the primary key,
+// and `Query.Out` and `Query.Scan` return one if the query has no rows.
+func (i *Invocation) Strict() bool {
+	return i.strict
+}
+
+// WithStrict sets the strict mode property and returns an invocation.
+func (i *Invocation) WithStrict(flag bool) *Invocation {
+	i.strict = flag
+	return i
+}
+
 // Err returns the context's error.
 func (i *Invocation) Err() error {
 	return i.err
@@ -70,18 +94,26 @@ func (i *Invocation) Tx() *sql.Tx {
 }
 
 // Prepare returns a cached or newly prepared statment plan for a given sql statement.
+// If statement comments are enabled, the statement is tagged with a comment before it's prepared.
 func (i *Invocation) Prepare(statement string) (*sql.Stmt, error) {
 	if i.err != nil {
 		return nil, i.err
 	}
 	if len(i.statementLabel) > 0 {
+		statement = i.conn.commentStatement(statement, i.statementLabel, i.ctx, i.conn.useStatementCache)
 		return i.conn.PrepareCached(i.statementLabel, statement, i.tx)
 	}
-	return i.conn.Prepare(statement, i.tx)
+	return i.conn.Prepare(i.conn.commentStatement(statement, "", i.ctx, false), i.tx)
 }
 
 // Exec executes a sql statement with a given set of arguments.
 func (i *Invocation) Exec(statement string, args ...interface{}) (err error) {
+	_, err = i.ExecWithResult(statement, args...)
+	return
+}
+
+// ExecWithResult executes a sql statement with a given set of arguments and returns the result.
+func (i *Invocation) ExecWithResult(statement string, args ...interface{}) (res sql.Result, err error) {
 	err = i.check()
 	if err != nil {
 		return
@@ -90,28 +122,13 @@ func (i *Invocation) Exec(statement string, args ...interface{}) (err error) {
 	start := time.Now()
 	defer func() { err = i.finalizer(recover(), err, FlagExecute, statement, start) }()
 
-	stmt, stmtErr := i.Prepare(statement)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
-		return
-	}
-
-	defer i.closeStatement(err, stmt)
-
-	if _, execErr := stmt.Exec(args...); execErr != nil {
-		err = exception.Wrap(execErr)
-		if err != nil {
-			i.invalidateCachedStatement()
-		}
-		return
-	}
-
+	res, err = i.execStatement(statement, args...)
 	return
 }
 
 // Query returns a new query object for a given sql query and arguments.
 func (i *Invocation) Query(query string, args ...interface{}) *Query {
-	return &Query{statement: query, args: args, start: time.Now(), conn: i.conn, ctx: i.ctx, tx: i.tx, fireEvents: i.fireEvents, err: i.check(), statementLabel: i.statementLabel}
+	return &Query{statement: query, args: args, start: time.Now(), conn: i.conn, ctx: i.ctx, tx: i.tx, fireEvents: i.fireEvents, strict: i.strict, err: i.check(), statementLabel: i.statementLabel}
 }
 
 // Get returns a given object based on a group of primary key ids within a transaction.
@@ -131,6 +148,7 @@ func (i *Invocation) Get(object DatabaseMapped, ids ...interface{}) (err error)
 	meta := getCachedColumnCollectionFromInstance(object)
 	standardCols := meta.NotReadOnly()
 	tableName := TableName(object)
+	i.tableName = tableName
 
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_get", tableName)
@@ -171,26 +189,12 @@ func (i *Invocation) Get(object DatabaseMapped, ids ...interface{}) (err error)
 	}
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
-		return
-	}
-	defer i.closeStatement(err, stmt)
-
-	var rows *sql.Rows
-	var queryErr error
-	if i.ctx != nil {
-		rows, queryErr = stmt.QueryContext(i.ctx, ids...)
-	} else {
-		rows, queryErr = stmt.Query(ids...)
-	}
-
+	stmt, rows, queryErr := i.queryStatement(queryBody, ids...)
 	if queryErr != nil {
-		err = exception.Wrap(queryErr)
-		i.invalidateCachedStatement()
+		err = queryErr
 		return
 	}
+	defer i.closeStatement(err, stmt)
 	defer func() {
 		closeErr := rows.Close()
 		if closeErr != nil {
@@ -198,8 +202,13 @@ func (i *Invocation) Get(object DatabaseMapped, ids ...interface{}) (err error)
 		}
 	}()
 
+	var rowsReturned int64
+	scanStart := time.Now()
+	defer func() { i.scanned(scanStart, rowsReturned) }()
+
 	var popErr error
 	if rows.Next() {
+		rowsReturned++
 		if isPopulatable(object) {
 			popErr = asPopulatable(object).Populate(rows)
 		} else {
@@ -230,6 +239,7 @@ func (i *Invocation) GetAll(collection interface{}) (err error) {
 	collectionValue := reflectValue(collection)
 	t := reflectSliceType(collection)
 	tableName := TableNameByType(t)
+	i.tableName = tableName
 
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_get_all", tableName)
@@ -253,25 +263,12 @@ func (i *Invocation) GetAll(collection interface{}) (err error) {
 	queryBodyBuffer.WriteString(tableName)
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
-		i.invalidateCachedStatement()
-		return
-	}
-	defer func() { err = i.closeStatement(err, stmt) }()
-
-	var rows *sql.Rows
-	var queryErr error
-	if i.ctx != nil {
-		rows, queryErr = stmt.QueryContext(i.ctx)
-	} else {
-		rows, queryErr = stmt.Query()
-	}
+	stmt, rows, queryErr := i.queryStatement(queryBody)
 	if queryErr != nil {
-		err = exception.Wrap(queryErr)
+		err = queryErr
 		return
 	}
+	defer func() { err = i.closeStatement(err, stmt) }()
 	defer func() {
 		closeErr := rows.Close()
 		if closeErr != nil {
@@ -285,8 +282,13 @@ func (i *Invocation) GetAll(collection interface{}) (err error) {
 	}
 	isPopulatable := isPopulatable(v)
 
+	var rowsReturned int64
+	scanStart := time.Now()
+	defer func() { i.scanned(scanStart, rowsReturned) }()
+
 	var popErr error
 	for rows.Next() {
+		rowsReturned++
 		newObj, _ := makeNewDatabaseMapped(t)
 
 		if isPopulatable {
@@ -307,6 +309,7 @@ func (i *Invocation) GetAll(collection interface{}) (err error) {
 }
 
 // Create writes an object to the database within a transaction.
+// Serial and `default` columns are read back into the object after it's written.
 func (i *Invocation) Create(object DatabaseMapped) (err error) {
 	err = i.check()
 	if err != nil {
@@ -318,15 +321,15 @@ func (i *Invocation) Create(object DatabaseMapped) (err error) {
 	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
 
 	cols := getCachedColumnCollectionFromInstance(object)
-	writeCols := cols.NotReadOnly().NotSerials()
-
-	//NOTE: we're only using one.
-	serials := cols.Serials()
+	writeCols, omitted := cols.NotReadOnly().NotSerials().NotZeroDefaults(object)
+	returning := cols.Returning()
 	tableName := TableName(object)
+	i.tableName = tableName
 
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_create", tableName)
 	}
+	i.labelOmitted(omitted)
 
 	colNames := writeCols.ColumnNames()
 	colValues := writeCols.ColumnValues(object)
@@ -336,64 +339,45 @@ func (i *Invocation) Create(object DatabaseMapped) (err error) {
 
 	queryBodyBuffer.WriteString("INSERT INTO ")
 	queryBodyBuffer.WriteString(tableName)
-	queryBodyBuffer.WriteString(" (")
-	for i, name := range colNames {
-		queryBodyBuffer.WriteString(name)
-		if i < len(colNames)-1 {
-			queryBodyBuffer.WriteRune(runeComma)
+	if writeCols.Len() == 0 {
+		queryBodyBuffer.WriteString(" DEFAULT VALUES")
+	} else {
+		queryBodyBuffer.WriteString(" (")
+		for i, name := range colNames {
+			queryBodyBuffer.WriteString(name)
+			if i < len(colNames)-1 {
+				queryBodyBuffer.WriteRune(runeComma)
+			}
 		}
-	}
-	queryBodyBuffer.WriteString(") VALUES (")
-	for x := 0; x < writeCols.Len(); x++ {
-		queryBodyBuffer.WriteString("$" + strconv.Itoa(x+1))
-		if x < (writeCols.Len() - 1) {
-			queryBodyBuffer.WriteRune(runeComma)
+		queryBodyBuffer.WriteString(") VALUES (")
+		for x := 0; x < writeCols.Len(); x++ {
+			queryBodyBuffer.WriteString("$" + strconv.Itoa(x+1))
+			if x < (writeCols.Len() - 1) {
+				queryBodyBuffer.WriteRune(runeComma)
+			}
 		}
+		queryBodyBuffer.WriteString(")")
 	}
-	queryBodyBuffer.WriteString(")")
 
-	if serials.Len() > 0 {
-		serial := serials.FirstOrDefault()
+	if returning.Len() > 0 {
 		queryBodyBuffer.WriteString(" RETURNING ")
-		queryBodyBuffer.WriteString(serial.ColumnName)
+		queryBodyBuffer.WriteString(returning.ColumnNamesCSV())
 	}
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
-		return
-	}
-	defer func() { err = i.closeStatement(err, stmt) }()
-
-	var execErr error
-	if serials.Len() == 0 {
-		if i.ctx != nil {
-			_, execErr = stmt.ExecContext(i.ctx, colValues...)
-		} else {
-			_, execErr = stmt.Exec(colValues...)
-		}
-
-		if execErr != nil {
-			err = exception.Wrap(execErr)
-			i.invalidateCachedStatement()
+	if returning.Len() == 0 {
+		_, err = i.execStatement(queryBody, colValues...)
+		if err != nil {
 			return
 		}
 	} else {
-		serial := serials.FirstOrDefault()
-
-		var id interface{}
-		if i.ctx != nil {
-			execErr = stmt.QueryRowContext(i.ctx, colValues...).Scan(&id)
-		} else {
-			execErr = stmt.QueryRow(colValues...).Scan(&id)
-		}
-
+		returned := newScanValues(returning)
+		execErr := i.scanStatement(queryBody, colValues, returned...)
 		if execErr != nil {
 			err = exception.Wrap(execErr)
 			return
 		}
-		setErr := serial.SetValue(object, id)
+		setErr := setScanValues(object, returning, returned)
 		if setErr != nil {
 			err = exception.Wrap(setErr)
 			return
@@ -421,6 +405,7 @@ func (i *Invocation) CreateIfNotExists(object DatabaseMapped) (err error) {
 	serials := cols.Serials()
 	pks := cols.PrimaryKeys()
 	tableName := TableName(object)
+	i.tableName = tableName
 
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_create_if_not_exists", tableName)
@@ -469,35 +454,16 @@ func (i *Invocation) CreateIfNotExists(object DatabaseMapped) (err error) {
 	}
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
-		return
-	}
-	defer func() { err = i.closeStatement(err, stmt) }()
-
-	var execErr error
 	if serials.Len() == 0 {
-		if i.ctx != nil {
-			_, execErr = stmt.ExecContext(i.ctx, colValues...)
-		} else {
-			_, execErr = stmt.Exec(colValues...)
-		}
-		if execErr != nil {
-			err = exception.Wrap(execErr)
-			i.invalidateCachedStatement()
+		_, err = i.execStatement(queryBody, colValues...)
+		if err != nil {
 			return
 		}
 	} else {
 		serial := serials.FirstOrDefault()
 
 		var id interface{}
-		if i.ctx != nil {
-			execErr = stmt.QueryRowContext(i.ctx, colValues...).Scan(&id)
-		} else {
-			execErr = stmt.QueryRow(colValues...).Scan(&id)
-		}
-
+		execErr := i.scanStatement(queryBody, colValues, &id)
 		if execErr != nil {
 			err = exception.Wrap(execErr)
 			return
@@ -530,6 +496,7 @@ func (i *Invocation) CreateMany(objects interface{}) (err error) {
 
 	sliceType := reflectSliceType(objects)
 	tableName := TableNameByType(sliceType)
+	i.tableName = tableName
 
 	cols := getCachedColumnCollectionFromType(tableName, sliceType)
 	writeCols := cols.NotReadOnly().NotSerials()
@@ -570,30 +537,130 @@ func (i *Invocation) CreateMany(objects interface{}) (err error) {
 	}
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
+
+	var colValues []interface{}
+	for row := 0; row < sliceValue.Len(); row++ {
+		colValues = append(colValues, writeCols.ColumnValues(sliceValue.Index(row).Interface())...)
+	}
+
+	_, err = i.execStatement(queryBody, colValues...)
+	return
+}
+
+// CopyIn bulk loads a slice of objects into their table with `COPY FROM STDIN`.
+// It is much faster than `CreateMany` for large collections; serial and readonly columns are skipped.
+// If the invocation does not have a transaction, one is started and committed for the copy.
+func (i *Invocation) CopyIn(collection interface{}) error {
+	sliceValue := reflectValue(collection)
+	var index int
+	return i.CopyInFrom(i.ctx, func() (DatabaseMapped, error) {
+		if index >= sliceValue.Len() {
+			return nil, nil
+		}
+		object := sliceValue.Index(index).Interface()
+		index = index + 1
+		return object, nil
+	})
+}
+
+// CopyInFrom streams objects from an iterator into their table with `COPY FROM STDIN`.
+// The table and columns are determined by the first object the iterator returns, and every
+// subsequent object must be of the same type.
+// If the invocation does not have a transaction, one is started and committed for the copy.
+func (i *Invocation) CopyInFrom(ctx context.Context, iterator CopyInIterator) (err error) {
+	err = i.check()
+	if err != nil {
+		return
+	}
+
+	var queryBody string
+	start := time.Now()
+	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
+
+	object, iteratorErr := iterator()
+	if iteratorErr != nil {
+		err = exception.Wrap(iteratorErr)
+		return
+	}
+	if object == nil {
+		return
+	}
+
+	tableName := TableName(object)
+	i.tableName = tableName
+	if len(i.statementLabel) == 0 {
+		i.statementLabel = fmt.Sprintf("%s_copy_in", tableName)
+	}
+	i.startSpan(SpanExec)
+
+	cols := getCachedColumnCollectionFromInstance(object)
+	writeCols := cols.NotReadOnly().NotSerials()
+	queryBody = pq.CopyIn(tableName, writeCols.ColumnNames()...)
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx := i.tx
+	if tx == nil {
+		var txErr error
+		tx, txErr = i.conn.BeginContext(ctx)
+		if txErr != nil {
+			err = exception.Wrap(txErr)
+			return
+		}
+		// this is a no-op if the transaction has been committed.
+		defer i.conn.Rollback(tx)
+	}
+
+	// copy statements are bound to the transaction and are never cached.
+	stmt, stmtErr := tx.PrepareContext(ctx, queryBody)
 	if stmtErr != nil {
 		err = exception.Wrap(stmtErr)
 		return
 	}
-	defer func() { err = i.closeStatement(err, stmt) }()
+	defer func() {
+		closeErr := stmt.Close()
+		if closeErr != nil {
+			err = exception.Nest(err, closeErr)
+		}
+	}()
 
-	var colValues []interface{}
-	for row := 0; row < sliceValue.Len(); row++ {
-		colValues = append(colValues, writeCols.ColumnValues(sliceValue.Index(row).Interface())...)
+	var execErr error
+	for object != nil {
+		if _, execErr = stmt.ExecContext(ctx, writeCols.ColumnValues(object)...); execErr != nil {
+			err = exception.Wrap(execErr)
+			return
+		}
+
+		object, iteratorErr = iterator()
+		if iteratorErr != nil {
+			err = exception.Wrap(iteratorErr)
+			return
+		}
 	}
 
-	_, execErr := stmt.Exec(colValues...)
-	if execErr != nil {
+	// an empty exec flushes the buffered rows to the server.
+	if _, execErr = stmt.ExecContext(ctx); execErr != nil {
 		err = exception.Wrap(execErr)
-		i.invalidateCachedStatement()
 		return
 	}
 
-	return nil
+	if i.tx == nil {
+		err = exception.Wrap(i.conn.Commit(tx))
+	}
+	return
 }
 
 // Update updates an object wrapped in a transaction.
+// Every column is written, including zero valued `default` columns, and every `default` column is read back into the object.
 func (i *Invocation) Update(object DatabaseMapped) (err error) {
+	_, err = i.UpdateWithResult(object)
+	return
+}
+
+// UpdateWithResult updates an object wrapped in a transaction and returns the result.
+func (i *Invocation) UpdateWithResult(object DatabaseMapped) (res sql.Result, err error) {
 	err = i.check()
 	if err != nil {
 		return
@@ -604,6 +671,7 @@ func (i *Invocation) Update(object DatabaseMapped) (err error) {
 	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
 
 	tableName := TableName(object)
+	i.tableName = tableName
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_update", tableName)
 	}
@@ -611,8 +679,8 @@ func (i *Invocation) Update(object DatabaseMapped) (err error) {
 	cols := getCachedColumnCollectionFromInstance(object)
 	writeCols := cols.WriteColumns()
 	pks := cols.PrimaryKeys()
-	updateCols := cols.UpdateColumns()
-	updateValues := updateCols.ColumnValues(object)
+	returning := cols.Defaults()
+	updateValues := append(writeCols.ColumnValues(object), pks.ColumnValues(object)...)
 	numColumns := writeCols.Len()
 
 	queryBodyBuffer := i.conn.bufferPool.Get()
@@ -644,27 +712,35 @@ func (i *Invocation) Update(object DatabaseMapped) (err error) {
 		}
 	}
 
+	if returning.Len() > 0 {
+		queryBodyBuffer.WriteString(" RETURNING ")
+		queryBodyBuffer.WriteString(returning.ColumnNamesCSV())
+	}
+
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
+	if returning.Len() == 0 {
+		res, err = i.execStatement(queryBody, updateValues...)
+		if err != nil {
+			return
+		}
+		err = i.checkRowsAffected(res)
 		return
 	}
 
-	defer func() { err = i.closeStatement(err, stmt) }()
-
-	var execErr error
-	if i.ctx != nil {
-		_, execErr = stmt.ExecContext(i.ctx, updateValues...)
-	} else {
-		_, execErr = stmt.Exec(updateValues...)
+	returned := newScanValues(returning)
+	execErr := i.scanStatement(queryBody, updateValues, returned...)
+	// no rows means nothing matched the primary key, which is only an error in strict mode.
+	if execErr == sql.ErrNoRows {
+		res = rowsAffectedResult(0)
+		err = i.checkRowsAffected(res)
+		return
 	}
 	if execErr != nil {
-		err = exception.Wrap(execErr)
-		i.invalidateCachedStatement()
+		err = execErr
 		return
 	}
-
+	res = rowsAffectedResult(1)
+	err = setScanValues(object, returning, returned)
 	return
 }
 
@@ -680,6 +756,7 @@ func (i *Invocation) Exists(object DatabaseMapped) (exists bool, err error) {
 	defer func() { err = i.finalizer(recover(), err, FlagQuery, queryBody, start) }()
 
 	tableName := TableName(object)
+	i.tableName = tableName
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_exists", tableName)
 	}
@@ -710,23 +787,14 @@ func (i *Invocation) Exists(object DatabaseMapped) (exists bool, err error) {
 	}
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
+	stmt, rows, queryErr := i.queryStatement(queryBody, pks.ColumnValues(object)...)
+	if queryErr != nil {
 		exists = false
-		err = exception.Wrap(stmtErr)
+		err = queryErr
 		return
 	}
 
 	defer func() { err = i.closeStatement(err, stmt) }()
-
-	pkValues := pks.ColumnValues(object)
-	var rows *sql.Rows
-	var queryErr error
-	if i.ctx != nil {
-		rows, queryErr = stmt.QueryContext(i.ctx, pkValues...)
-	} else {
-		rows, queryErr = stmt.Query(pkValues...)
-	}
 	defer func() {
 		closeErr := rows.Close()
 		if closeErr != nil {
@@ -734,19 +802,24 @@ func (i *Invocation) Exists(object DatabaseMapped) (exists bool, err error) {
 		}
 	}()
 
-	if queryErr != nil {
-		exists = false
-		err = exception.Wrap(queryErr)
-		i.invalidateCachedStatement()
-		return
-	}
-
+	scanStart := time.Now()
 	exists = rows.Next()
+	if exists {
+		i.scanned(scanStart, 1)
+	} else {
+		i.scanned(scanStart, 0)
+	}
 	return
 }
 
 // Delete deletes an object from the database wrapped in a transaction.
 func (i *Invocation) Delete(object DatabaseMapped) (err error) {
+	_, err = i.DeleteWithResult(object)
+	return
+}
+
+// DeleteWithResult deletes an object from the database wrapped in a transaction and returns the result.
+func (i *Invocation) DeleteWithResult(object DatabaseMapped) (res sql.Result, err error) {
 	err = i.check()
 	if err != nil {
 		return
@@ -757,6 +830,7 @@ func (i *Invocation) Delete(object DatabaseMapped) (err error) {
 	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
 
 	tableName := TableName(object)
+	i.tableName = tableName
 
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_delete", tableName)
@@ -788,25 +862,11 @@ func (i *Invocation) Delete(object DatabaseMapped) (err error) {
 	}
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
+	res, err = i.execStatement(queryBody, pks.ColumnValues(object)...)
+	if err != nil {
 		return
 	}
-	defer func() { err = i.closeStatement(err, stmt) }()
-
-	pkValues := pks.ColumnValues(object)
-
-	var execErr error
-	if i.ctx != nil {
-		_, execErr = stmt.ExecContext(i.ctx, pkValues...)
-	} else {
-		_, execErr = stmt.Exec(pkValues...)
-	}
-	if execErr != nil {
-		err = exception.Wrap(execErr)
-		i.invalidateCachedStatement()
-	}
+	err = i.checkRowsAffected(res)
 	return
 }
 
@@ -822,6 +882,7 @@ func (i *Invocation) Truncate(object DatabaseMapped) (err error) {
 	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
 
 	tableName := TableName(object)
+	i.tableName = tableName
 
 	if len(i.statementLabel) == 0 {
 		i.statementLabel = fmt.Sprintf("%s_truncate", tableName)
@@ -834,29 +895,27 @@ func (i *Invocation) Truncate(object DatabaseMapped) (err error) {
 	queryBodyBuffer.WriteString(tableName)
 
 	queryBody = queryBodyBuffer.String()
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
-		return
-	}
-	defer func() { err = i.closeStatement(err, stmt) }()
-
-	var execErr error
-	if i.ctx != nil {
-		_, execErr = stmt.ExecContext(i.ctx)
-	} else {
-		_, execErr = stmt.Exec()
-	}
-
-	if execErr != nil {
-		err = exception.Wrap(execErr)
-		i.invalidateCachedStatement()
-	}
+	_, err = i.execStatement(queryBody)
 	return
 }
 
 // Upsert inserts the object if it doesn't exist already (as defined by its primary keys) or updates it wrapped in a transaction.
 func (i *Invocation) Upsert(object DatabaseMapped) (err error) {
+	_, err = i.upsert(object, OnConflict(), "upsert")
+	return
+}
+
+// UpsertWith inserts the object or resolves the conflict as described by `conflict`, returning if the row was inserted, updated or skipped.
+// Unlike `Upsert`, the statement is only cached if the invocation is given a label with `WithLabel`.
+func (i *Invocation) UpsertWith(object DatabaseMapped, conflict *Conflict) (result UpsertResult, err error) {
+	if conflict == nil {
+		conflict = OnConflict()
+	}
+	return i.upsert(object, conflict, "")
+}
+
+// upsert is the implementation of `Upsert` and `UpsertWith`; `labelSuffix` is used to create a default statement label.
+func (i *Invocation) upsert(object DatabaseMapped, conflict *Conflict, labelSuffix string) (result UpsertResult, err error) {
 	err = i.check()
 	if err != nil {
 		return
@@ -867,17 +926,16 @@ func (i *Invocation) Upsert(object DatabaseMapped) (err error) {
 	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
 
 	cols := getCachedColumnCollectionFromInstance(object)
-	writeCols := cols.NotReadOnly().NotSerials()
+	writeCols, omitted := cols.NotReadOnly().NotSerials().NotZeroDefaults(object)
 
-	conflictUpdateCols := cols.NotReadOnly().NotSerials().NotPrimaryKeys()
-
-	serials := cols.Serials()
-	pks := cols.PrimaryKeys()
+	returning := cols.Returning()
 	tableName := TableName(object)
+	i.tableName = tableName
 
-	if len(i.statementLabel) == 0 {
-		i.statementLabel = fmt.Sprintf("%s_upsert", tableName)
+	if len(i.statementLabel) == 0 && len(labelSuffix) > 0 {
+		i.statementLabel = fmt.Sprintf("%s_%s", tableName, labelSuffix)
 	}
+	i.labelOmitted(omitted)
 
 	colNames := writeCols.ColumnNames()
 	colValues := writeCols.ColumnValues(object)
@@ -896,8 +954,10 @@ func (i *Invocation) Upsert(object DatabaseMapped) (err error) {
 	}
 	queryBodyBuffer.WriteString(") VALUES (")
 
-	for x := 0; x < writeCols.Len(); x++ {
-		queryBodyBuffer.WriteString("$" + strconv.Itoa(x+1))
+	tokenMap := map[string]string{}
+	for x, col := range writeCols.Columns() {
+		tokenMap[col.ColumnName] = "$" + strconv.Itoa(x+1)
+		queryBodyBuffer.WriteString(tokenMap[col.ColumnName])
 		if x < (writeCols.Len() - 1) {
 			queryBodyBuffer.WriteRune(runeComma)
 		}
@@ -905,77 +965,232 @@ func (i *Invocation) Upsert(object DatabaseMapped) (err error) {
 
 	queryBodyBuffer.WriteString(")")
 
-	if pks.Len() > 0 {
-		tokenMap := map[string]string{}
-		for i, col := range writeCols.Columns() {
-			tokenMap[col.ColumnName] = "$" + strconv.Itoa(i+1)
+	if conflictErr := conflict.writeClause(queryBodyBuffer, cols, tokenMap); conflictErr != nil {
+		err = exception.Wrap(conflictErr)
+		return
+	}
+
+	// `xmax` is only zero for rows that were inserted by this statement.
+	queryBodyBuffer.WriteString(" RETURNING (xmax = 0)")
+	if returning.Len() != 0 {
+		queryBodyBuffer.WriteRune(runeComma)
+		queryBodyBuffer.WriteString(returning.ColumnNamesCSV())
+	}
+
+	queryBody = queryBodyBuffer.String()
+
+	var inserted bool
+	returned := newScanValues(returning)
+	scanValues := append([]interface{}{&inserted}, returned...)
+
+	execErr := i.scanStatement(queryBody, colValues, scanValues...)
+	if execErr == sql.ErrNoRows {
+		result = UpsertResultSkipped
+		return
+	}
+	if execErr != nil {
+		err = execErr
+		return
+	}
+
+	setErr := setScanValues(object, returning, returned)
+	if setErr != nil {
+		err = exception.Wrap(setErr)
+		return
+	}
+
+	if inserted {
+		result = UpsertResultInserted
+	} else {
+		result = UpsertResultUpdated
+	}
+	return
+}
+
+// UpsertMany inserts or updates a slice of objects in batched statements, returning the number of rows affected.
+// Conflicts are resolved on the primary key columns, and the remaining writable columns are updated from `excluded`.
+// Serial columns are not read back, and a single call must not contain two objects with the same primary key.
+// Batches are only applied atomically if the invocation has a transaction.
+func (i *Invocation) UpsertMany(objects interface{}) (affected int64, err error) {
+	err = i.check()
+	if err != nil {
+		return
+	}
+
+	var queryBody string
+	start := time.Now()
+	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
+
+	sliceValue := reflectValue(objects)
+	if sliceValue.Len() == 0 {
+		return
+	}
+
+	sliceType := reflectSliceType(objects)
+	tableName := TableNameByType(sliceType)
+	i.tableName = tableName
+
+	if len(i.statementLabel) == 0 {
+		i.statementLabel = fmt.Sprintf("%s_upsert_many", tableName)
+	}
+
+	cols := getCachedColumnCollectionFromType(tableName, sliceType)
+	writeCols := cols.NotReadOnly().NotSerials()
+	conflictUpdateCols := writeCols.NotPrimaryKeys()
+	pks := cols.PrimaryKeys()
+
+	if pks.Len() == 0 {
+		err = exception.New("no primary key on object to upsert by.")
+		return
+	}
+
+	total := sliceValue.Len()
+	batchRows := batchSize(writeCols.Len())
+
+	var rowsAffected int64
+	for offset := 0; offset < total; offset = offset + batchRows {
+		rowCount := batchRows
+		if offset+rowCount > total {
+			rowCount = total - offset
 		}
 
-		queryBodyBuffer.WriteString(" ON CONFLICT (")
-		pkColumnNames := pks.ColumnNames()
-		for i, name := range pkColumnNames {
-			queryBodyBuffer.WriteString(name)
-			if i < len(pkColumnNames)-1 {
+		queryBodyBuffer := i.conn.bufferPool.Get()
+		queryBodyBuffer.WriteString("INSERT INTO ")
+		queryBodyBuffer.WriteString(tableName)
+		queryBodyBuffer.WriteString(" (")
+		queryBodyBuffer.WriteString(writeCols.ColumnNamesCSV())
+		queryBodyBuffer.WriteString(") VALUES ")
+
+		metaIndex := 1
+		for x := 0; x < rowCount; x++ {
+			queryBodyBuffer.WriteString("(")
+			for y := 0; y < writeCols.Len(); y++ {
+				queryBodyBuffer.WriteString("$" + strconv.Itoa(metaIndex))
+				metaIndex = metaIndex + 1
+				if y < writeCols.Len()-1 {
+					queryBodyBuffer.WriteRune(runeComma)
+				}
+			}
+			queryBodyBuffer.WriteString(")")
+			if x < rowCount-1 {
 				queryBodyBuffer.WriteRune(runeComma)
 			}
 		}
-		queryBodyBuffer.WriteString(") DO UPDATE SET ")
 
-		conflictCols := conflictUpdateCols.Columns()
-		for i, col := range conflictCols {
-			queryBodyBuffer.WriteString(col.ColumnName + " = " + tokenMap[col.ColumnName])
-			if i < (len(conflictCols) - 1) {
-				queryBodyBuffer.WriteRune(runeComma)
+		queryBodyBuffer.WriteString(" ON CONFLICT (")
+		queryBodyBuffer.WriteString(pks.ColumnNamesCSV())
+		if conflictUpdateCols.Len() == 0 {
+			queryBodyBuffer.WriteString(") DO NOTHING")
+		} else {
+			queryBodyBuffer.WriteString(") DO UPDATE SET ")
+			conflictCols := conflictUpdateCols.Columns()
+			for x, col := range conflictCols {
+				queryBodyBuffer.WriteString(col.ColumnName + " = excluded." + col.ColumnName)
+				if x < (len(conflictCols) - 1) {
+					queryBodyBuffer.WriteRune(runeComma)
+				}
 			}
 		}
+
+		queryBody = queryBodyBuffer.String()
+		i.conn.bufferPool.Put(queryBodyBuffer)
+
+		var colValues []interface{}
+		for row := offset; row < offset+rowCount; row++ {
+			colValues = append(colValues, writeCols.ColumnValues(sliceValue.Index(row).Interface())...)
+		}
+
+		rowsAffected, err = i.execBatch(queryBody, colValues)
+		if err != nil {
+			return
+		}
+		affected = affected + rowsAffected
 	}
+	return
+}
 
-	var serial = serials.FirstOrDefault()
-	if serials.Len() != 0 {
-		queryBodyBuffer.WriteString(" RETURNING ")
-		queryBodyBuffer.WriteString(serial.ColumnName)
+// DeleteMany deletes a slice of objects by their primary keys in batched statements, returning the number of rows affected.
+// Batches are only applied atomically if the invocation has a transaction.
+func (i *Invocation) DeleteMany(objects interface{}) (affected int64, err error) {
+	err = i.check()
+	if err != nil {
+		return
 	}
 
-	queryBody = queryBodyBuffer.String()
+	var queryBody string
+	start := time.Now()
+	defer func() { err = i.finalizer(recover(), err, FlagExecute, queryBody, start) }()
 
-	stmt, stmtErr := i.Prepare(queryBody)
-	if stmtErr != nil {
-		err = exception.Wrap(stmtErr)
+	sliceValue := reflectValue(objects)
+	if sliceValue.Len() == 0 {
 		return
 	}
-	defer func() { err = i.closeStatement(err, stmt) }()
 
-	var execErr error
-	if serials.Len() != 0 {
-		var id interface{}
-		if i.ctx != nil {
-			execErr = stmt.QueryRowContext(i.ctx, colValues...).Scan(&id)
-		} else {
-			execErr = stmt.QueryRow(colValues...).Scan(&id)
-		}
-		if execErr != nil {
-			err = exception.Wrap(execErr)
-			i.invalidateCachedStatement()
-			return
+	sliceType := reflectSliceType(objects)
+	tableName := TableNameByType(sliceType)
+	i.tableName = tableName
+
+	if len(i.statementLabel) == 0 {
+		i.statementLabel = fmt.Sprintf("%s_delete_many", tableName)
+	}
+
+	cols := getCachedColumnCollectionFromType(tableName, sliceType)
+	pks := cols.PrimaryKeys()
+
+	if pks.Len() == 0 {
+		err = exception.New("No primary key on object.")
+		return
+	}
+
+	total := sliceValue.Len()
+	batchRows := batchSize(pks.Len())
+
+	var rowsAffected int64
+	for offset := 0; offset < total; offset = offset + batchRows {
+		rowCount := batchRows
+		if offset+rowCount > total {
+			rowCount = total - offset
 		}
-		setErr := serial.SetValue(object, id)
-		if setErr != nil {
-			err = exception.Wrap(setErr)
-			return
+
+		queryBodyBuffer := i.conn.bufferPool.Get()
+		queryBodyBuffer.WriteString("DELETE FROM ")
+		queryBodyBuffer.WriteString(tableName)
+		queryBodyBuffer.WriteString(" WHERE (")
+		queryBodyBuffer.WriteString(pks.ColumnNamesCSV())
+		queryBodyBuffer.WriteString(") IN (")
+
+		metaIndex := 1
+		for x := 0; x < rowCount; x++ {
+			queryBodyBuffer.WriteString("(")
+			for y := 0; y < pks.Len(); y++ {
+				queryBodyBuffer.WriteString("$" + strconv.Itoa(metaIndex))
+				metaIndex = metaIndex + 1
+				if y < pks.Len()-1 {
+					queryBodyBuffer.WriteRune(runeComma)
+				}
+			}
+			queryBodyBuffer.WriteString(")")
+			if x < rowCount-1 {
+				queryBodyBuffer.WriteRune(runeComma)
+			}
 		}
-	} else {
-		if i.ctx != nil {
-			_, execErr = stmt.ExecContext(i.ctx, colValues...)
-		} else {
-			_, execErr = stmt.Exec(colValues...)
+		queryBodyBuffer.WriteString(")")
+
+		queryBody = queryBodyBuffer.String()
+		i.conn.bufferPool.Put(queryBodyBuffer)
+
+		var pkValues []interface{}
+		for row := offset; row < offset+rowCount; row++ {
+			pkValues = append(pkValues, pks.ColumnValues(sliceValue.Index(row).Interface())...)
 		}
-		if execErr != nil {
-			err = exception.Wrap(execErr)
+
+		rowsAffected, err = i.execBatch(queryBody, pkValues)
+		if err != nil {
 			return
 		}
+		affected = affected + rowsAffected
 	}
-
-	return nil
+	return
 }
 
 // --------------------------------------------------------------------------------
@@ -992,14 +1207,216 @@ func (i *Invocation) check() error {
 	return nil
 }
 
-func (i *Invocation) invalidateCachedStatement() {
-	if i.conn.useStatementCache && len(i.statementLabel) > 0 {
+// checkRowsAffected returns a `NotFoundError` in strict mode if a result did not affect any rows.
+func (i *Invocation) checkRowsAffected(res sql.Result) error {
+	if !i.strict {
+		return nil
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	if rowsAffected == 0 {
+		return &NotFoundError{Label: i.statementLabel}
+	}
+	return nil
+}
+
+// labelOmitted qualifies the statement label with the names of `default` columns that were left out of the statement,
+// so that each variation of the statement is cached separately.
+func (i *Invocation) labelOmitted(omitted []string) {
+	i.statementLabel = omittedLabel(i.statementLabel, omitted)
+}
+
+// omittedLabel returns a statement label qualified with the names of `default` columns that were left out of the statement,
+// ex: `users_create_omit_created_utc`.
+func omittedLabel(label string, omitted []string) string {
+	if len(label) > 0 && len(omitted) > 0 {
+		return label + "_omit_" + strings.Join(omitted, "_")
+	}
+	return label
+}
+
+// invalidateCachedStatement removes and closes the cached statement after it failed with a given error.
+// Statements that failed because they were closed are already out of the cache, and whatever is cached
+// under the label now was prepared by another caller.
+func (i *Invocation) invalidateCachedStatement(err error) {
+	if i.conn.useStatementCache && len(i.statementLabel) > 0 && !isStatementClosed(err) {
 		i.conn.statementCache.InvalidateStatement(i.statementLabel)
 	}
 }
 
+// shouldRetry returns if a statement that failed with a given error should be prepared again and retried.
+// Only cached statements outside of transactions are retried, if their plan is stale or they were closed by
+// the cache while in use; within a transaction the error has already aborted it.
+func (i *Invocation) shouldRetry(err error) bool {
+	return i.tx == nil && i.conn.useStatementCache && len(i.statementLabel) > 0 && (IsStalePlan(err) || isStatementClosed(err))
+}
+
+// withStatement prepares a statement and runs an action with it.
+// If the action fails, a cached statement is invalidated; if it failed because the cached plan is stale after a schema change,
+// or because the cache closed the statement while it was in use, the statement is prepared again and the action is retried once.
+// The statement is returned for the caller to close unless there is an error other than `sql.ErrNoRows`.
+func (i *Invocation) withStatement(statement string, action func(*sql.Stmt) error) (*sql.Stmt, error) {
+	i.details.cached = i.conn.useStatementCache && len(i.statementLabel) > 0
+	stmt, err := i.prepareTimed(statement)
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+
+	err = i.runTimed(stmt, action)
+	if err == nil || err == sql.ErrNoRows {
+		return stmt, err
+	}
+	i.invalidateCachedStatement(err)
+
+	if i.shouldRetry(err) {
+		// the statement is no longer cached and can't be used again.
+		stmt.Close()
+		stmt, err = i.prepareTimed(statement)
+		if err != nil {
+			return nil, exception.Wrap(err)
+		}
+
+		err = i.runTimed(stmt, action)
+		if err == nil || err == sql.ErrNoRows {
+			return stmt, err
+		}
+		i.invalidateCachedStatement(err)
+	}
+
+	return nil, exception.Nest(exception.Wrap(err), i.closeStatement(nil, stmt))
+}
+
+// prepareTimed prepares a statement in a prepare span, adding the time it took to the event details.
+func (i *Invocation) prepareTimed(statement string) (stmt *sql.Stmt, err error) {
+	span, _ := i.conn.Tracer().StartSpan(i.spanContext(), SpanPrepare)
+	i.tagSpan(span)
+	start := time.Now()
+	defer func() {
+		i.details.prepareElapsed = i.details.prepareElapsed + time.Since(start)
+		finishSpan(span, err)
+	}()
+	stmt, err = i.Prepare(statement)
+	return
+}
+
+// runTimed runs an action with a statement, adding the time it took to the event details.
+func (i *Invocation) runTimed(stmt *sql.Stmt, action func(*sql.Stmt) error) error {
+	start := time.Now()
+	defer func() { i.details.executeElapsed = i.details.executeElapsed + time.Since(start) }()
+	return action(stmt)
+}
+
+// execStatement prepares and executes a statement.
+func (i *Invocation) execStatement(statement string, args ...interface{}) (res sql.Result, err error) {
+	i.startSpan(SpanExec)
+	i.details.args = args
+	stmt, err := i.withStatement(statement, func(stmt *sql.Stmt) (execErr error) {
+		if i.ctx != nil {
+			res, execErr = stmt.ExecContext(i.ctx, args...)
+		} else {
+			res, execErr = stmt.Exec(args...)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	if rowsAffected, rowsErr := res.RowsAffected(); rowsErr == nil {
+		i.details.addRowsAffected(rowsAffected)
+	}
+	err = i.closeStatement(err, stmt)
+	return
+}
+
+// scanStatement prepares and runs a statement that returns a single row, scanning the row into a set of values.
+// It returns `sql.ErrNoRows` as is if there is no row.
+func (i *Invocation) scanStatement(statement string, args []interface{}, values ...interface{}) error {
+	i.startSpan(SpanQuery)
+	i.details.args = args
+	stmt, err := i.withStatement(statement, func(stmt *sql.Stmt) error {
+		if i.ctx != nil {
+			return stmt.QueryRowContext(i.ctx, args...).Scan(values...)
+		}
+		return stmt.QueryRow(args...).Scan(values...)
+	})
+	if stmt == nil {
+		return err
+	}
+	if err == nil {
+		i.details.addRowsReturned(1)
+	} else {
+		i.details.addRowsReturned(0)
+	}
+	return i.closeStatement(err, stmt)
+}
+
+// queryStatement prepares and runs a statement that returns rows.
+// The caller must close the rows, and then the statement with `closeStatement`; time spent reading the rows
+// should be recorded with `scanned`.
+func (i *Invocation) queryStatement(statement string, args ...interface{}) (stmt *sql.Stmt, rows *sql.Rows, err error) {
+	i.startSpan(SpanQuery)
+	i.details.args = args
+	stmt, err = i.withStatement(statement, func(stmt *sql.Stmt) (queryErr error) {
+		if i.ctx != nil {
+			rows, queryErr = stmt.QueryContext(i.ctx, args...)
+		} else {
+			rows, queryErr = stmt.Query(args...)
+		}
+		return
+	})
+	return
+}
+
+// startSpan starts the span for the invocation's statement, unless it has already been started.
+// Statements in a tracked transaction are children of the transaction's span.
+// The span is finished by the finalizer, after any rows are closed.
+func (i *Invocation) startSpan(operation string) {
+	if i.span != nil {
+		return
+	}
+	ctx := i.ctx
+	if txCtx := i.conn.txSpanContext(i.tx); txCtx != nil {
+		ctx = txCtx
+	} else if ctx == nil {
+		ctx = context.Background()
+	}
+	i.span, i.spanCtx = i.conn.Tracer().StartSpan(ctx, operation)
+	i.tagSpan(i.span)
+}
+
+// spanContext returns the context that carries the invocation's span, for starting child spans.
+func (i *Invocation) spanContext() context.Context {
+	if i.spanCtx != nil {
+		return i.spanCtx
+	}
+	if i.ctx != nil {
+		return i.ctx
+	}
+	return context.Background()
+}
+
+// tagSpan tags a span with the statement label and table name.
+func (i *Invocation) tagSpan(span Span) {
+	if len(i.statementLabel) > 0 {
+		span.SetTag(TagLabel, i.statementLabel)
+	}
+	if len(i.tableName) > 0 {
+		span.SetTag(TagTable, i.tableName)
+	}
+}
+
+// scanned adds the time spent reading rows since a given start, and the number of rows read, to the event details.
+func (i *Invocation) scanned(start time.Time, rowsReturned int64) {
+	i.details.scanElapsed = i.details.scanElapsed + time.Since(start)
+	i.details.addRowsReturned(rowsReturned)
+}
+
+// closeStatement closes a statement unless it belongs to the statement cache.
+// Statements used within a transaction are always closed; closing a statement bound with `tx.Stmt` leaves the cached statement open.
 func (i *Invocation) closeStatement(err error, stmt *sql.Stmt) error {
-	if !i.conn.useStatementCache {
+	if !i.conn.useStatementCache || i.tx != nil {
 		closeErr := stmt.Close()
 		if closeErr != nil {
 			return exception.Nest(err, closeErr)
@@ -1008,14 +1425,66 @@ func (i *Invocation) closeStatement(err error, stmt *sql.Stmt) error {
 	return err
 }
 
+// execBatch prepares and runs a batch statement, returning the number of rows affected.
+// Batch statements vary with the number of rows in the batch, so they are never cached.
+func (i *Invocation) execBatch(statement string, args []interface{}) (affected int64, err error) {
+	i.startSpan(SpanExec)
+	i.details.args = args
+	prepareStart := time.Now()
+	stmt, stmtErr := i.conn.Prepare(i.conn.commentStatement(statement, i.statementLabel, i.ctx, false), i.tx)
+	i.details.prepareElapsed = i.details.prepareElapsed + time.Since(prepareStart)
+	if stmtErr != nil {
+		err = exception.Wrap(stmtErr)
+		return
+	}
+	defer func() {
+		closeErr := stmt.Close()
+		if closeErr != nil {
+			err = exception.Nest(err, closeErr)
+		}
+	}()
+
+	var res sql.Result
+	var execErr error
+	executeStart := time.Now()
+	if i.ctx != nil {
+		res, execErr = stmt.ExecContext(i.ctx, args...)
+	} else {
+		res, execErr = stmt.Exec(args...)
+	}
+	i.details.executeElapsed = i.details.executeElapsed + time.Since(executeStart)
+	if execErr != nil {
+		err = exception.Wrap(execErr)
+		return
+	}
+
+	affected, err = res.RowsAffected()
+	if err == nil {
+		i.details.addRowsAffected(affected)
+	}
+	err = exception.Wrap(err)
+	return
+}
+
 func (i *Invocation) finalizer(r interface{}, err error, flag logger.Flag, statement string, start time.Time) error {
 	if r != nil {
 		recoveryException := exception.New(r)
 		err = exception.Nest(err, recoveryException)
 	}
+	elapsed := time.Now().Sub(start)
+	i.conn.txStatement(i.tx)
+	i.conn.observeStatement(i.statementLabel, statement, elapsed, err)
+	i.conn.checkSlowQuery(i.statementLabel, statement, i.details.args, elapsed)
 	if i.fireEvents {
-		i.conn.fireEvent(flag, statement, time.Now().Sub(start), err, i.statementLabel)
+		i.conn.fireEvent(flag, statement, i.statementLabel, elapsed, err, i.details, i.ctx, i.tx)
+	}
+	if i.span != nil {
+		finishSpan(i.span, err)
 	}
 	i.statementLabel = ""
+	i.tableName = ""
+	i.details = eventDetails{}
+	i.span = nil
+	i.spanCtx = nil
 	return err
 }
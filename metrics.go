@@ -0,0 +1,210 @@
+package spiffy
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MetricsUnlabeled is the label that statements without a label are counted under.
+	MetricsUnlabeled = "unlabeled"
+
+	// MetricsContentType is the content type of the prometheus text exposition format.
+	MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultLatencyBuckets are the default upper bounds, in seconds, of the statement latency histograms.
+var DefaultLatencyBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MetricsCollector observes the statements a connection runs.
+type MetricsCollector interface {
+	// ObserveStatement is called once for every statement with its label, elapsed time and error, if any.
+	ObserveStatement(label string, elapsed time.Duration, err error)
+}
+
+// NewMetrics returns a new metrics collector with the default latency buckets.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		lock:    &sync.Mutex{},
+		buckets: DefaultLatencyBuckets,
+		labels:  map[string]*LabelMetrics{},
+	}
+}
+
+// Metrics aggregates per-label statement counts, error counts and latency histograms.
+type Metrics struct {
+	lock    *sync.Mutex
+	buckets []float64
+	labels  map[string]*LabelMetrics
+}
+
+// LabelMetrics are the aggregated metrics for a statement label.
+type LabelMetrics struct {
+	Label  string
+	Count  int64
+	Errors int64
+	// Sum is the total elapsed time of the statements in seconds.
+	Sum float64
+	// Buckets are the cumulative counts of statements that took at most the corresponding latency bucket.
+	Buckets []int64
+}
+
+// WithBuckets sets the upper bounds, in seconds, of the latency histograms and resets the collected metrics.
+// The buckets must be sorted in ascending order.
+func (m *Metrics) WithBuckets(buckets ...float64) *Metrics {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.buckets = buckets
+	m.labels = map[string]*LabelMetrics{}
+	return m
+}
+
+// Buckets returns the upper bounds, in seconds, of the latency histograms.
+func (m *Metrics) Buckets() []float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.buckets
+}
+
+// ObserveStatement implements `MetricsCollector`.
+func (m *Metrics) ObserveStatement(label string, elapsed time.Duration, err error) {
+	if len(label) == 0 {
+		label = MetricsUnlabeled
+	}
+	seconds := elapsed.Seconds()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	lm, hasLabel := m.labels[label]
+	if !hasLabel {
+		lm = &LabelMetrics{Label: label, Buckets: make([]int64, len(m.buckets))}
+		m.labels[label] = lm
+	}
+	lm.Count = lm.Count + 1
+	if err != nil {
+		lm.Errors = lm.Errors + 1
+	}
+	lm.Sum = lm.Sum + seconds
+	for index, upperBound := range m.buckets {
+		if seconds <= upperBound {
+			lm.Buckets[index] = lm.Buckets[index] + 1
+		}
+	}
+}
+
+// Labels returns a snapshot of the metrics for each label, sorted by label.
+func (m *Metrics) Labels() []LabelMetrics {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	labels := make([]LabelMetrics, 0, len(m.labels))
+	for _, lm := range m.labels {
+		copied := *lm
+		copied.Buckets = append([]int64(nil), lm.Buckets...)
+		labels = append(labels, copied)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Label < labels[j].Label })
+	return labels
+}
+
+// Reset clears the collected metrics.
+func (m *Metrics) Reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.labels = map[string]*LabelMetrics{}
+}
+
+// WritePrometheus writes the metrics, and the connection pool stats if given, in the prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer, stats *sql.DBStats) error {
+	buffer := bufio.NewWriter(w)
+	buckets := m.Buckets()
+	labels := m.Labels()
+
+	writeMetricHeader(buffer, "spiffy_statements_total", "counter", "The number of statements run, by statement label.")
+	for _, lm := range labels {
+		fmt.Fprintf(buffer, "spiffy_statements_total{label=%s} %d\n", quoteLabelValue(lm.Label), lm.Count)
+	}
+
+	writeMetricHeader(buffer, "spiffy_statement_errors_total", "counter", "The number of statements that returned an error, by statement label.")
+	for _, lm := range labels {
+		fmt.Fprintf(buffer, "spiffy_statement_errors_total{label=%s} %d\n", quoteLabelValue(lm.Label), lm.Errors)
+	}
+
+	writeMetricHeader(buffer, "spiffy_statement_duration_seconds", "histogram", "The elapsed time of statements, by statement label.")
+	for _, lm := range labels {
+		label := quoteLabelValue(lm.Label)
+		for index, upperBound := range buckets {
+			fmt.Fprintf(buffer, "spiffy_statement_duration_seconds_bucket{label=%s,le=\"%s\"} %d\n", label, formatFloat(upperBound), lm.Buckets[index])
+		}
+		fmt.Fprintf(buffer, "spiffy_statement_duration_seconds_bucket{label=%s,le=\"+Inf\"} %d\n", label, lm.Count)
+		fmt.Fprintf(buffer, "spiffy_statement_duration_seconds_sum{label=%s} %s\n", label, formatFloat(lm.Sum))
+		fmt.Fprintf(buffer, "spiffy_statement_duration_seconds_count{label=%s} %d\n", label, lm.Count)
+	}
+
+	if stats != nil {
+		writeGauge(buffer, "spiffy_pool_max_open_connections", "The maximum number of open connections to the database.", int64(stats.MaxOpenConnections))
+		writeGauge(buffer, "spiffy_pool_open_connections", "The number of established connections, both in use and idle.", int64(stats.OpenConnections))
+		writeGauge(buffer, "spiffy_pool_in_use_connections", "The number of connections currently in use.", int64(stats.InUse))
+		writeGauge(buffer, "spiffy_pool_idle_connections", "The number of idle connections.", int64(stats.Idle))
+		writeCounter(buffer, "spiffy_pool_wait_count_total", "The total number of connections waited for.", stats.WaitCount)
+		writeMetricHeader(buffer, "spiffy_pool_wait_duration_seconds_total", "counter", "The total time blocked waiting for a new connection.")
+		fmt.Fprintf(buffer, "spiffy_pool_wait_duration_seconds_total %s\n", formatFloat(stats.WaitDuration.Seconds()))
+		writeCounter(buffer, "spiffy_pool_max_idle_closed_total", "The total number of connections closed due to the max idle connections.", stats.MaxIdleClosed)
+		writeCounter(buffer, "spiffy_pool_max_lifetime_closed_total", "The total number of connections closed due to the max connection lifetime.", stats.MaxLifetimeClosed)
+	}
+	return buffer.Flush()
+}
+
+// NewMetricsHandler returns an http handler that renders metrics, and the pool stats of a connection if it is open,
+// in the prometheus text exposition format.
+func NewMetricsHandler(metrics *Metrics, conn *Connection) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var stats *sql.DBStats
+		if conn != nil && conn.Connection != nil {
+			dbStats := conn.Connection.Stats()
+			stats = &dbStats
+		}
+		rw.Header().Set("Content-Type", MetricsContentType)
+		rw.WriteHeader(http.StatusOK)
+		metrics.WritePrometheus(rw, stats)
+	})
+}
+
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+func writeGauge(w io.Writer, name, help string, value int64) {
+	writeSample(w, name, "gauge", help, value)
+}
+
+func writeCounter(w io.Writer, name, help string, value int64) {
+	writeSample(w, name, "counter", help, value)
+}
+
+func writeSample(w io.Writer, name, metricType, help string, value int64) {
+	writeMetricHeader(w, name, metricType, help)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+// quoteLabelValue quotes a prometheus label value, escaping backslashes, quotes and newlines.
+func quoteLabelValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	return `"` + value + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
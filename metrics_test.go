@@ -0,0 +1,82 @@
+package spiffy
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMetricsObserveStatement(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := NewMetrics().WithBuckets(0.01, 0.1, 1)
+	metrics.ObserveStatement("b_label", 5*time.Millisecond, nil)
+	metrics.ObserveStatement("b_label", 50*time.Millisecond, fmt.Errorf("test"))
+	metrics.ObserveStatement("a_label", 2*time.Second, nil)
+	metrics.ObserveStatement("", time.Millisecond, nil)
+
+	labels := metrics.Labels()
+	assert.Len(labels, 3)
+	assert.Equal("a_label", labels[0].Label)
+	assert.Equal([]int64{0, 0, 0}, labels[0].Buckets)
+	assert.Equal("b_label", labels[1].Label)
+	assert.Equal(int64(2), labels[1].Count)
+	assert.Equal(int64(1), labels[1].Errors)
+	assert.Equal([]int64{1, 2, 2}, labels[1].Buckets)
+	assert.Equal(MetricsUnlabeled, labels[2].Label)
+
+	metrics.Reset()
+	assert.Empty(metrics.Labels())
+}
+
+func TestMetricsWritePrometheus(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := NewMetrics().WithBuckets(0.01, 0.1)
+	metrics.ObserveStatement(`quoted"label`, 5*time.Millisecond, fmt.Errorf("test"))
+
+	buffer := bytes.NewBuffer(nil)
+	err := metrics.WritePrometheus(buffer, &sql.DBStats{MaxOpenConnections: 10, InUse: 2})
+	assert.Nil(err)
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, "# TYPE spiffy_statement_duration_seconds histogram\n"))
+	assert.True(strings.Contains(output, `spiffy_statements_total{label="quoted\"label"} 1`+"\n"))
+	assert.True(strings.Contains(output, `spiffy_statement_errors_total{label="quoted\"label"} 1`+"\n"))
+	assert.True(strings.Contains(output, `spiffy_statement_duration_seconds_bucket{label="quoted\"label",le="0.01"} 1`+"\n"))
+	assert.True(strings.Contains(output, `spiffy_statement_duration_seconds_bucket{label="quoted\"label",le="+Inf"} 1`+"\n"))
+	assert.True(strings.Contains(output, "spiffy_pool_max_open_connections 10\n"))
+	assert.True(strings.Contains(output, "spiffy_pool_in_use_connections 2\n"))
+	assert.True(strings.Contains(output, "# TYPE spiffy_pool_in_use_connections gauge\n"))
+	assert.True(strings.Contains(output, "# TYPE spiffy_pool_wait_count_total counter\n"))
+	assert.True(strings.Contains(output, "# TYPE spiffy_pool_max_lifetime_closed_total counter\n"))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := NewFromEnv()
+	defer conn.Close()
+	metrics := NewMetrics()
+	conn.WithMetrics(metrics)
+
+	err := conn.Invoke().WithLabel("metrics_test").Exec("select 1")
+	assert.Nil(err)
+	// statements labeled with their own text are counted as unlabeled.
+	err = conn.Exec("select 2")
+	assert.Nil(err)
+
+	res := httptest.NewRecorder()
+	NewMetricsHandler(metrics, conn).ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(MetricsContentType, res.Header().Get("Content-Type"))
+	assert.True(strings.Contains(res.Body.String(), `spiffy_statements_total{label="metrics_test"} 1`))
+	assert.True(strings.Contains(res.Body.String(), `spiffy_statements_total{label="unlabeled"} 1`))
+	assert.False(strings.Contains(res.Body.String(), "select 2"))
+	assert.True(strings.Contains(res.Body.String(), "spiffy_pool_open_connections"))
+}
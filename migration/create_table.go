@@ -0,0 +1,27 @@
+package migration
+
+import (
+	"database/sql"
+
+	"github.com/blendlabs/spiffy"
+)
+
+// CreateTable returns a step that creates a mapped object's table, and its indexes, if the table doesn't exist.
+// The statements are generated with `spiffy.CreateTableDDL` when the step runs.
+func CreateTable(object spiffy.DatabaseMapped) *Step {
+	return NewStep(TableNotExists(spiffy.TableName(object)), &createTable{object: object})
+}
+
+// createTable is an invocable that runs the generated DDL for a mapped object's table.
+type createTable struct {
+	object spiffy.DatabaseMapped
+}
+
+// Invoke generates and runs the statements.
+func (ct *createTable) Invoke(c *spiffy.Connection, tx *sql.Tx) error {
+	statements, err := spiffy.CreateTableDDL(ct.object)
+	if err != nil {
+		return err
+	}
+	return Statements(statements...).Invoke(c, tx)
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/spiffy"
+)
+
+type createTableObj struct {
+	ID   int    `db:"id,pk,serial"`
+	Name string `db:"name" db_index:""`
+}
+
+func (cto createTableObj) TableName() string {
+	return "migration_create_table_obj"
+}
+
+func TestCreateTableStep(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := spiffy.Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	assert.Nil(CreateTable(createTableObj{}).Apply(spiffy.Default(), tx))
+	exists, err := tableExists(spiffy.Default(), tx, createTableObj{}.TableName())
+	assert.Nil(err)
+	assert.True(exists)
+	exists, err = indexExists(spiffy.Default(), tx, createTableObj{}.TableName(), "ix_migration_create_table_obj_name")
+	assert.Nil(err)
+	assert.True(exists)
+
+	// the table exists, so the step is skipped.
+	assert.Nil(CreateTable(createTableObj{}).Apply(spiffy.Default(), tx))
+}
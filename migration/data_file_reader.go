@@ -112,7 +112,7 @@ func (dfr *DataFileReader) Test(c *spiffy.Connection, optionalTx ...*sql.Tx) (er
 		} else {
 			dfr.logger.Error(dfr, err)
 		}
-		tx.Rollback()
+		c.Rollback(tx)
 	}()
 	err = dfr.Invoke(c, tx)
 	return
@@ -129,10 +129,10 @@ func (dfr *DataFileReader) Apply(c *spiffy.Connection, optionalTx ...*sql.Tx) (e
 			err = fmt.Errorf("%v", err)
 		}
 		if err == nil {
-			tx.Commit()
+			c.Commit(tx)
 			dfr.logger.Applyf(dfr, "done")
 		} else {
-			tx.Rollback()
+			c.Rollback(tx)
 			dfr.logger.Error(dfr, err)
 		}
 	}()
@@ -0,0 +1,279 @@
+package migration
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"strings"
+
+	exception "github.com/blendlabs/go-exception"
+	"github.com/blendlabs/spiffy"
+)
+
+// ChangeKind is the kind of change that brings a table up to date with its mapped type.
+type ChangeKind string
+
+const (
+	// ChangeCreateTable creates a missing table, and its indexes.
+	ChangeCreateTable ChangeKind = "create_table"
+	// ChangeAddColumn adds a missing column.
+	ChangeAddColumn ChangeKind = "add_column"
+	// ChangeCreateIndex creates a missing index.
+	ChangeCreateIndex ChangeKind = "create_index"
+	// ChangeDropColumn drops a column that isn't mapped.
+	ChangeDropColumn ChangeKind = "drop_column"
+	// ChangeAlterColumnType changes the data type of a column.
+	ChangeAlterColumnType ChangeKind = "alter_column_type"
+	// ChangeAlterColumnNullability sets or drops `NOT NULL` on a column.
+	ChangeAlterColumnNullability ChangeKind = "alter_column_nullability"
+)
+
+// Change is a change that brings a table up to date with its mapped type.
+type Change struct {
+	Kind   ChangeKind
+	Table  string
+	Column string
+	Index  string
+	// Statements are the statements that make the change; for destructive changes they are a suggestion.
+	Statements []string
+	// IsDestructive is set for changes to existing columns, and for added `NOT NULL` columns without a default,
+	// which can lose data or fail on existing rows, and are left to be handled manually.
+	IsDestructive bool
+}
+
+// String returns a description of the change, ex: `add_column users.email`.
+func (c Change) String() string {
+	switch {
+	case len(c.Index) > 0:
+		return fmt.Sprintf("%s %s on %s", c.Kind, c.Index, c.Table)
+	case len(c.Column) > 0:
+		return fmt.Sprintf("%s %s.%s", c.Kind, c.Table, c.Column)
+	}
+	return fmt.Sprintf("%s %s", c.Kind, c.Table)
+}
+
+// Step returns a guarded step that makes an additive change, or nil for destructive changes.
+func (c Change) Step() *Step {
+	switch c.Kind {
+	case ChangeCreateTable:
+		return NewStep(TableNotExists(c.Table), Statements(c.Statements...))
+	case ChangeAddColumn:
+		return NewStep(ColumnNotExists(c.Table, c.Column), Statements(c.Statements...))
+	case ChangeCreateIndex:
+		return NewStep(IndexNotExists(c.Table, c.Index), Statements(c.Statements...))
+	}
+	return nil
+}
+
+// guardSource returns the go source of the guard for an additive change.
+func (c Change) guardSource() string {
+	switch c.Kind {
+	case ChangeCreateTable:
+		return fmt.Sprintf("migration.TableNotExists(%q)", c.Table)
+	case ChangeAddColumn:
+		return fmt.Sprintf("migration.ColumnNotExists(%q, %q)", c.Table, c.Column)
+	case ChangeCreateIndex:
+		return fmt.Sprintf("migration.IndexNotExists(%q, %q)", c.Table, c.Index)
+	}
+	return ""
+}
+
+// Diff compares mapped types with the tables in the current schema, and returns the changes that would bring the
+// tables up to date; missing tables, columns and indexes are additive changes, and columns that aren't mapped,
+// or whose type or nullability don't match, are destructive changes. The guards of the steps it generates check
+// the current schema too, so a same-named table in another schema doesn't skip them.
+func Diff(c *spiffy.Connection, objects ...spiffy.DatabaseMapped) (*SchemaDiff, error) {
+	return DiffInTx(c, nil, objects...)
+}
+
+// DiffInTx compares mapped types with the tables in the current schema within a transaction.
+func DiffInTx(c *spiffy.Connection, tx *sql.Tx, objects ...spiffy.DatabaseMapped) (*SchemaDiff, error) {
+	diff := &SchemaDiff{}
+	introspector := c.IntrospectInTx("", tx)
+	for _, object := range objects {
+		drift, err := c.CheckSchemaInTx(tx, object)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := driftChanges(object, drift)
+		if err != nil {
+			return nil, err
+		}
+		diff.Changes = append(diff.Changes, changes...)
+		if len(changes) > 0 && changes[0].Kind == ChangeCreateTable {
+			continue
+		}
+
+		for _, index := range spiffy.TableIndexes(object) {
+			exists, err := introspector.IndexExists(strings.ToLower(index.Table), strings.ToLower(index.Name))
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				diff.Changes = append(diff.Changes, Change{Kind: ChangeCreateIndex, Table: index.Table, Index: index.Name, Statements: []string{index.Statement()}})
+			}
+		}
+	}
+	return diff, nil
+}
+
+// driftChanges returns the changes that fix the drift between a mapped type and its table.
+func driftChanges(object spiffy.DatabaseMapped, drift []spiffy.SchemaDrift) ([]Change, error) {
+	cols := map[string]spiffy.Column{}
+	for _, col := range spiffy.Columns(object).Columns() {
+		cols[col.ColumnName] = col
+	}
+
+	var changes []Change
+	for _, d := range drift {
+		switch d.Kind {
+		case spiffy.SchemaDriftMissingTable:
+			statements, err := spiffy.CreateTableDDL(object)
+			if err != nil {
+				return nil, err
+			}
+			return []Change{{Kind: ChangeCreateTable, Table: d.Table, Statements: statements}}, nil
+		case spiffy.SchemaDriftMissingColumn:
+			definition, err := spiffy.ColumnDefinition(cols[d.Column])
+			if err != nil {
+				return nil, err
+			}
+			col := cols[d.Column]
+			changes = append(changes, Change{Kind: ChangeAddColumn, Table: d.Table, Column: d.Column, IsDestructive: isNotNullWithoutDefault(col), Statements: []string{
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.Table, definition),
+			}})
+		case spiffy.SchemaDriftExtraColumn:
+			changes = append(changes, Change{Kind: ChangeDropColumn, Table: d.Table, Column: d.Column, IsDestructive: true, Statements: []string{
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.Table, d.Column),
+			}})
+		case spiffy.SchemaDriftTypeMismatch:
+			change := Change{Kind: ChangeAlterColumnType, Table: d.Table, Column: d.Column, IsDestructive: true}
+			if dataType, err := spiffy.ColumnDataType(cols[d.Column]); err == nil {
+				change.Statements = []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", d.Table, d.Column, dataType)}
+			}
+			changes = append(changes, change)
+		case spiffy.SchemaDriftNullabilityMismatch:
+			action := "DROP NOT NULL"
+			if d.Expected == "not null" {
+				action = "SET NOT NULL"
+			}
+			changes = append(changes, Change{Kind: ChangeAlterColumnNullability, Table: d.Table, Column: d.Column, IsDestructive: true, Statements: []string{
+				fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", d.Table, d.Column, action),
+			}})
+		}
+	}
+	return changes, nil
+}
+
+// SchemaDiff is the set of changes that bring tables up to date with their mapped types.
+type SchemaDiff struct {
+	Changes []Change
+}
+
+// IsEmpty returns if there are no changes.
+func (sd *SchemaDiff) IsEmpty() bool {
+	return len(sd.Changes) == 0
+}
+
+// Additive returns the changes that only add tables, columns or indexes.
+func (sd *SchemaDiff) Additive() []Change {
+	var changes []Change
+	for _, change := range sd.Changes {
+		if !change.IsDestructive {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Destructive returns the changes that need to be handled manually.
+func (sd *SchemaDiff) Destructive() []Change {
+	var changes []Change
+	for _, change := range sd.Changes {
+		if change.IsDestructive {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Group returns a migration group with a guarded step for each additive change.
+func (sd *SchemaDiff) Group() *Group {
+	group := NewGroup()
+	for _, change := range sd.Additive() {
+		group.Add(change.Step())
+	}
+	return group
+}
+
+// GoSource returns the formatted source of a go file with a function that returns a migration group with a guarded
+// step for each additive change. Destructive changes are listed in the function's comment, with suggested statements.
+func (sd *SchemaDiff) GoSource(packageName, funcName string) ([]byte, error) {
+	source := bytes.NewBuffer(nil)
+	fmt.Fprintf(source, "package %s\n\n", packageName)
+	fmt.Fprint(source, "import \"github.com/blendlabs/spiffy/migration\"\n\n")
+
+	fmt.Fprintf(source, "// %s returns the migrations that bring tables up to date with their mapped types.\n", funcName)
+	if destructive := sd.Destructive(); len(destructive) > 0 {
+		fmt.Fprint(source, "//\n// These destructive changes need to be handled manually:\n")
+		for _, change := range destructive {
+			fmt.Fprintf(source, "//   - %s\n", change)
+			for _, statement := range change.Statements {
+				fmt.Fprintf(source, "//     %s\n", statement)
+			}
+		}
+	}
+	fmt.Fprintf(source, "func %s() *migration.Group {\n", funcName)
+	fmt.Fprint(source, "\treturn migration.NewGroup(\n")
+	for _, change := range sd.Additive() {
+		fmt.Fprint(source, "\t\tmigration.NewStep(\n")
+		fmt.Fprintf(source, "\t\t\t%s,\n", change.guardSource())
+		fmt.Fprint(source, "\t\t\tmigration.Statements(\n")
+		for _, statement := range change.Statements {
+			fmt.Fprintf(source, "\t\t\t\t%s,\n", goStringLiteral(statement))
+		}
+		fmt.Fprint(source, "\t\t\t),\n\t\t),\n")
+	}
+	fmt.Fprint(source, "\t)\n}\n")
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return formatted, nil
+}
+
+// SQL returns the statements for the additive changes, with the destructive changes' statements commented out.
+func (sd *SchemaDiff) SQL() string {
+	output := bytes.NewBuffer(nil)
+	for _, change := range sd.Additive() {
+		fmt.Fprintf(output, "-- %s\n", change)
+		for _, statement := range change.Statements {
+			fmt.Fprintf(output, "%s;\n", statement)
+		}
+		fmt.Fprintln(output)
+	}
+	for _, change := range sd.Destructive() {
+		fmt.Fprintf(output, "-- destructive, handle manually: %s\n", change)
+		for _, statement := range change.Statements {
+			fmt.Fprintf(output, "-- %s;\n", statement)
+		}
+		fmt.Fprintln(output)
+	}
+	return output.String()
+}
+
+// goStringLiteral returns a go string literal for a statement; a raw string if it spans lines.
+func goStringLiteral(statement string) string {
+	if strings.Contains(statement, "\n") && !strings.Contains(statement, "`") {
+		return "`" + statement + "`"
+	}
+	return fmt.Sprintf("%q", statement)
+}
+
+// isNotNullWithoutDefault returns if a column is `NOT NULL` without a default, which can't be added to a table with rows.
+func isNotNullWithoutDefault(col spiffy.Column) bool {
+	return (col.IsPrimaryKey || !spiffy.ColumnIsNullable(col)) && len(col.DefaultValue) == 0
+}
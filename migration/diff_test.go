@@ -0,0 +1,128 @@
+package migration
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/spiffy"
+)
+
+type diffObj struct {
+	ID       int     `db:"id,pk,serial"`
+	OwnerID  *int64  `db:"owner_id" db_index:""`
+	Priority int     `db:"priority"`
+	Title    *string `db:"title"`
+}
+
+func (do diffObj) TableName() string {
+	return "migration_diff_obj"
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := spiffy.Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	diff, err := DiffInTx(spiffy.Default(), tx, diffObj{})
+	assert.Nil(err)
+	assert.Len(diff.Changes, 1)
+	assert.Equal(ChangeCreateTable, diff.Changes[0].Kind)
+	assert.Len(diff.Changes[0].Statements, 2)
+
+	assert.Nil(spiffy.Default().ExecInTx("CREATE TABLE migration_diff_obj (id serial not null, legacy text, title text not null)", tx))
+	diff, err = DiffInTx(spiffy.Default(), tx, diffObj{})
+	assert.Nil(err)
+	assert.Equal([]Change{
+		{Kind: ChangeAddColumn, Table: "migration_diff_obj", Column: "owner_id", Statements: []string{"ALTER TABLE migration_diff_obj ADD COLUMN owner_id bigint"}},
+		{Kind: ChangeCreateIndex, Table: "migration_diff_obj", Index: "ix_migration_diff_obj_owner_id", Statements: []string{"CREATE INDEX ix_migration_diff_obj_owner_id ON migration_diff_obj (owner_id)"}},
+	}, diff.Additive())
+	assert.Equal([]Change{
+		{Kind: ChangeAddColumn, Table: "migration_diff_obj", Column: "priority", IsDestructive: true, Statements: []string{"ALTER TABLE migration_diff_obj ADD COLUMN priority bigint NOT NULL"}},
+		{Kind: ChangeAlterColumnNullability, Table: "migration_diff_obj", Column: "title", IsDestructive: true, Statements: []string{"ALTER TABLE migration_diff_obj ALTER COLUMN title DROP NOT NULL"}},
+		{Kind: ChangeDropColumn, Table: "migration_diff_obj", Column: "legacy", IsDestructive: true, Statements: []string{"ALTER TABLE migration_diff_obj DROP COLUMN legacy"}},
+	}, diff.Destructive())
+
+	assert.Nil(diff.Group().Apply(spiffy.Default(), tx))
+	diff, err = DiffInTx(spiffy.Default(), tx, diffObj{})
+	assert.Nil(err)
+	assert.Empty(diff.Additive())
+	assert.Len(diff.Destructive(), 3)
+}
+
+type diffDefaultsObj struct {
+	ID       int     `db:"id,pk,serial"`
+	Priority int     `db:"priority"`
+	Status   string  `db:"status" db_default:"'active'"`
+	Note     *string `db:"note"`
+}
+
+func (ddo diffDefaultsObj) TableName() string {
+	return "migration_diff_defaults_obj"
+}
+
+func TestDriftChangesAddNotNullColumn(t *testing.T) {
+	assert := assert.New(t)
+
+	changes, err := driftChanges(diffDefaultsObj{}, []spiffy.SchemaDrift{
+		{Kind: spiffy.SchemaDriftMissingColumn, Table: "migration_diff_defaults_obj", Column: "priority"},
+		{Kind: spiffy.SchemaDriftMissingColumn, Table: "migration_diff_defaults_obj", Column: "status"},
+		{Kind: spiffy.SchemaDriftMissingColumn, Table: "migration_diff_defaults_obj", Column: "note"},
+	})
+	assert.Nil(err)
+	assert.Equal([]Change{
+		{Kind: ChangeAddColumn, Table: "migration_diff_defaults_obj", Column: "priority", IsDestructive: true, Statements: []string{"ALTER TABLE migration_diff_defaults_obj ADD COLUMN priority bigint NOT NULL"}},
+		{Kind: ChangeAddColumn, Table: "migration_diff_defaults_obj", Column: "status", Statements: []string{"ALTER TABLE migration_diff_defaults_obj ADD COLUMN status text NOT NULL DEFAULT 'active'"}},
+		{Kind: ChangeAddColumn, Table: "migration_diff_defaults_obj", Column: "note", Statements: []string{"ALTER TABLE migration_diff_defaults_obj ADD COLUMN note text"}},
+	}, changes)
+}
+
+func TestSchemaDiffGoSource(t *testing.T) {
+	assert := assert.New(t)
+
+	diff := &SchemaDiff{Changes: []Change{
+		{Kind: ChangeAddColumn, Table: "users", Column: "email", Statements: []string{"ALTER TABLE users ADD COLUMN email text NOT NULL"}},
+		{Kind: ChangeCreateIndex, Table: "users", Index: "ix_users_email", Statements: []string{"CREATE INDEX ix_users_email ON users (email)"}},
+		{Kind: ChangeDropColumn, Table: "users", Column: "legacy", IsDestructive: true, Statements: []string{"ALTER TABLE users DROP COLUMN legacy"}},
+	}}
+
+	source, err := diff.GoSource("migrations", "Users")
+	assert.Nil(err)
+	assert.Equal(`package migrations
+
+import "github.com/blendlabs/spiffy/migration"
+
+// Users returns the migrations that bring tables up to date with their mapped types.
+//
+// These destructive changes need to be handled manually:
+//   - drop_column users.legacy
+//     ALTER TABLE users DROP COLUMN legacy
+func Users() *migration.Group {
+	return migration.NewGroup(
+		migration.NewStep(
+			migration.ColumnNotExists("users", "email"),
+			migration.Statements(
+				"ALTER TABLE users ADD COLUMN email text NOT NULL",
+			),
+		),
+		migration.NewStep(
+			migration.IndexNotExists("users", "ix_users_email"),
+			migration.Statements(
+				"CREATE INDEX ix_users_email ON users (email)",
+			),
+		),
+	)
+}
+`, string(source))
+
+	assert.Equal(`-- add_column users.email
+ALTER TABLE users ADD COLUMN email text NOT NULL;
+
+-- create_index ix_users_email on users
+CREATE INDEX ix_users_email ON users (email);
+
+-- destructive, handle manually: drop_column users.legacy
+-- ALTER TABLE users DROP COLUMN legacy;
+
+`, diff.SQL())
+}
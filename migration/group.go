@@ -180,9 +180,9 @@ func (g *Group) invoke(isTest bool, m Migration, c *spiffy.Connection, optionalT
 	}
 	defer func() {
 		if err == nil {
-			err = exception.Wrap(tx.Commit())
+			err = exception.Wrap(c.Commit(tx))
 		} else {
-			err = exception.Nest(err, exception.New(tx.Rollback()))
+			err = exception.Nest(err, exception.New(c.Rollback(tx)))
 		}
 	}()
 	err = m.Apply(c, tx)
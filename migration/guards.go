@@ -74,28 +74,28 @@ func IfNotExists(statement string) Guard {
 // ColumnNotExists creates a table on the given connection if it does not exist.
 func ColumnNotExists(tableName, columnName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl2(s, verbCreate, nounColumn, columnExists, tableName, columnName, c, tx)
+		return guardImpl2(s, verbCreate, nounColumn, s.scoped2(columnExists, anySchemaColumnExists), tableName, columnName, c, tx)
 	}
 }
 
 // ConstraintNotExists creates a table on the given connection if it does not exist.
 func ConstraintNotExists(constraintName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl1(s, verbCreate, nounConstraint, constraintExists, constraintName, c, tx)
+		return guardImpl1(s, verbCreate, nounConstraint, s.scoped1(constraintExists, anySchemaConstraintExists), constraintName, c, tx)
 	}
 }
 
 // TableNotExists creates a table on the given connection if it does not exist.
 func TableNotExists(tableName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl1(s, verbCreate, nounTable, tableExists, tableName, c, tx)
+		return guardImpl1(s, verbCreate, nounTable, s.scoped1(tableExists, anySchemaTableExists), tableName, c, tx)
 	}
 }
 
 // IndexNotExists creates a index on the given connection if it does not exist.
 func IndexNotExists(tableName, indexName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl2(s, verbCreate, nounIndex, indexExists, tableName, indexName, c, tx)
+		return guardImpl2(s, verbCreate, nounIndex, s.scoped2(indexExists, anySchemaIndexExists), tableName, indexName, c, tx)
 	}
 }
 
@@ -109,28 +109,28 @@ func RoleNotExists(roleName string) Guard {
 // ColumnExists alters an existing column, erroring if it doesn't exist
 func ColumnExists(tableName, columnName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl2(s, verbAlter, nounTable, columnExists, tableName, columnName, c, tx)
+		return guardImpl2(s, verbAlter, nounTable, s.scoped2(columnExists, anySchemaColumnExists), tableName, columnName, c, tx)
 	}
 }
 
 // ConstraintExists alters an existing constraint, erroring if it doesn't exist
 func ConstraintExists(constraintName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl1(s, verbAlter, nounConstraint, constraintExists, constraintName, c, tx)
+		return guardImpl1(s, verbAlter, nounConstraint, s.scoped1(constraintExists, anySchemaConstraintExists), constraintName, c, tx)
 	}
 }
 
 // TableExists alters an existing table, erroring if it doesn't exist
 func TableExists(tableName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl1(s, verbAlter, nounTable, tableExists, tableName, c, tx)
+		return guardImpl1(s, verbAlter, nounTable, s.scoped1(tableExists, anySchemaTableExists), tableName, c, tx)
 	}
 }
 
 // IndexExists alters an existing index, erroring if it doesn't exist
 func IndexExists(tableName, indexName string) Guard {
 	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
-		return guardImpl2(s, verbAlter, nounIndex, indexExists, tableName, indexName, c, tx)
+		return guardImpl2(s, verbAlter, nounIndex, s.scoped2(indexExists, anySchemaIndexExists), tableName, indexName, c, tx)
 	}
 }
 
@@ -141,6 +141,18 @@ func RoleExists(roleName string) Guard {
 	}
 }
 
+// AnySchema makes a table, column, index or constraint guard look for its subject in every schema, rather than only
+// in the current schema, as guards did before they were scoped to it.
+// A guard scoped to every schema can be satisfied by another schema's table, ex: `TableNotExists` skips creating a table
+// in the current schema if `public` has one of the same name.
+func AnySchema(guard Guard) Guard {
+	return func(s *Step, c *spiffy.Connection, tx *sql.Tx) error {
+		s.anySchema = true
+		defer func() { s.anySchema = false }()
+		return guard(s, c, tx)
+	}
+}
+
 // actionName joins a noun and a verb
 func actionName(verb, noun string) string {
 	return fmt.Sprintf("%v %v", verb, noun)
@@ -152,6 +164,22 @@ type guard1 func(c *spiffy.Connection, tx *sql.Tx, arg string) (bool, error)
 // guard2 is for guards that require (2) args such as `create column` and `create index`
 type guard2 func(c *spiffy.Connection, tx *sql.Tx, arg1, arg2 string) (bool, error)
 
+// scoped1 returns the check scoped to the current schema, or to every schema if the step's guard is wrapped with `AnySchema`.
+func (s *Step) scoped1(currentSchema, anySchema guard1) guard1 {
+	if s.anySchema {
+		return anySchema
+	}
+	return currentSchema
+}
+
+// scoped2 returns the check scoped to the current schema, or to every schema if the step's guard is wrapped with `AnySchema`.
+func (s *Step) scoped2(currentSchema, anySchema guard2) guard2 {
+	if s.anySchema {
+		return anySchema
+	}
+	return currentSchema
+}
+
 // actionImpl is an unguarded action, it doesn't care if something exists or doesn't
 // it is a requirement of the operation to guard itself.
 func guardImpl(s *Step, verb, noun string, c *spiffy.Connection, tx *sql.Tx) error {
@@ -207,23 +235,43 @@ func guardImpl2(s *Step, verb, noun string, guard guard2, subject1, subject2 str
 // Guards Implementations
 // --------------------------------------------------------------------------------
 
-// TableExists returns if a table exists on the given connection.
+// TableExists returns if a table exists in the current schema on the given connection.
 func tableExists(c *spiffy.Connection, tx *sql.Tx, tableName string) (bool, error) {
-	return c.QueryInTx(`SELECT 1 FROM pg_catalog.pg_tables WHERE tablename = $1`, tx, strings.ToLower(tableName)).Any()
+	return c.IntrospectInTx("", tx).TableExists(strings.ToLower(tableName))
 }
 
-// ColumnExists returns if a column exists on a table on the given connection.
+// ColumnExists returns if a column exists on a table in the current schema on the given connection.
 func columnExists(c *spiffy.Connection, tx *sql.Tx, tableName, columnName string) (bool, error) {
-	return c.QueryInTx(`SELECT 1 FROM information_schema.columns i WHERE i.table_name = $1 and i.column_name = $2`, tx, strings.ToLower(tableName), strings.ToLower(columnName)).Any()
+	return c.IntrospectInTx("", tx).ColumnExists(strings.ToLower(tableName), strings.ToLower(columnName))
 }
 
-// ConstraintExists returns if a constraint exists on a table on the given connection.
+// ConstraintExists returns if a constraint exists in the current schema on the given connection.
 func constraintExists(c *spiffy.Connection, tx *sql.Tx, constraintName string) (bool, error) {
-	return c.QueryInTx(`SELECT 1 FROM pg_constraint WHERE conname = $1`, tx, strings.ToLower(constraintName)).Any()
+	return c.IntrospectInTx("", tx).ConstraintExists(strings.ToLower(constraintName))
 }
 
-// IndexExists returns if a index exists on a table on the given connection.
+// IndexExists returns if a index exists on a table in the current schema on the given connection.
 func indexExists(c *spiffy.Connection, tx *sql.Tx, tableName, indexName string) (bool, error) {
+	return c.IntrospectInTx("", tx).IndexExists(strings.ToLower(tableName), strings.ToLower(indexName))
+}
+
+// anySchemaTableExists returns if a table exists in any schema on the given connection.
+func anySchemaTableExists(c *spiffy.Connection, tx *sql.Tx, tableName string) (bool, error) {
+	return c.QueryInTx(`SELECT 1 FROM pg_catalog.pg_tables WHERE tablename = $1`, tx, strings.ToLower(tableName)).Any()
+}
+
+// anySchemaColumnExists returns if a column exists on a table in any schema on the given connection.
+func anySchemaColumnExists(c *spiffy.Connection, tx *sql.Tx, tableName, columnName string) (bool, error) {
+	return c.QueryInTx(`SELECT 1 FROM information_schema.columns i WHERE i.table_name = $1 and i.column_name = $2`, tx, strings.ToLower(tableName), strings.ToLower(columnName)).Any()
+}
+
+// anySchemaConstraintExists returns if a constraint exists in any schema on the given connection.
+func anySchemaConstraintExists(c *spiffy.Connection, tx *sql.Tx, constraintName string) (bool, error) {
+	return c.QueryInTx(`SELECT 1 FROM pg_constraint WHERE conname = $1`, tx, strings.ToLower(constraintName)).Any()
+}
+
+// anySchemaIndexExists returns if a index exists on a table in any schema on the given connection.
+func anySchemaIndexExists(c *spiffy.Connection, tx *sql.Tx, tableName, indexName string) (bool, error) {
 	return c.QueryInTx(`SELECT 1 FROM pg_catalog.pg_index ix join pg_catalog.pg_class t on t.oid = ix.indrelid join pg_catalog.pg_class i on i.oid = ix.indexrelid WHERE t.relname = $1 and i.relname = $2 and t.relkind = 'r'`, tx, strings.ToLower(tableName), strings.ToLower(indexName)).Any()
 }
 
@@ -197,3 +197,32 @@ func TestGuard(t *testing.T) {
 	assert.Nil(err)
 	assert.True(didRun)
 }
+
+func TestGuardsScopedToCurrentSchema(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := spiffy.Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	// a table of the same name in another schema doesn't satisfy the guard.
+	schemaName, tableName := randomName(), randomName()
+	assert.Nil(spiffy.Default().ExecInTx(fmt.Sprintf("CREATE SCHEMA %s", schemaName), tx))
+	assert.Nil(spiffy.Default().ExecInTx(fmt.Sprintf("CREATE TABLE %s.%s (id int)", schemaName, tableName), tx))
+
+	exists, err := tableExists(spiffy.Default(), tx, tableName)
+	assert.Nil(err)
+	assert.False(exists)
+
+	var didRun bool
+	action := Body(func(c *spiffy.Connection, itx *sql.Tx) error {
+		didRun = true
+		return nil
+	})
+	assert.Nil(AnySchema(TableNotExists(tableName))(&Step{body: action}, spiffy.Default(), tx))
+	assert.False(didRun)
+
+	assert.Nil(createTestTable(tableName, tx))
+	exists, err = tableExists(spiffy.Default(), tx, tableName)
+	assert.Nil(err)
+	assert.True(exists)
+}
@@ -22,6 +22,9 @@ type Step struct {
 
 	guard Guard
 	body  Invocable
+
+	// anySchema is set while a guard wrapped with `AnySchema` runs.
+	anySchema bool
 }
 
 // Label returns the operation label.
@@ -98,3 +98,29 @@ func PopulateInOrder(object DatabaseMapped, row *sql.Rows, cols *ColumnCollectio
 
 	return nil
 }
+
+// newScanValues returns a set of scan destinations for the given columns, as used by `PopulateInOrder`.
+func newScanValues(cols *ColumnCollection) []interface{} {
+	var values = make([]interface{}, cols.Len())
+	for i, col := range cols.Columns() {
+		if col.IsJSON {
+			str := ""
+			values[i] = &str
+		} else {
+			values[i] = reflect.New(reflect.PtrTo(col.FieldType)).Interface()
+		}
+	}
+	return values
+}
+
+// setScanValues sets the values scanned into destinations from `newScanValues` on an object.
+func setScanValues(object DatabaseMapped, cols *ColumnCollection, values []interface{}) error {
+	columns := cols.Columns()
+	for i, v := range values {
+		err := columns[i].SetValue(object, v)
+		if err != nil {
+			return exception.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -24,14 +24,28 @@ type Query struct {
 
 	stmt       *sql.Stmt
 	fireEvents bool
+	strict     bool
 	conn       *Connection
 	ctx        context.Context
 	tx         *sql.Tx
 	err        error
+
+	details   eventDetails
+	scanStart time.Time
+	span      Span
+	spanCtx   context.Context
 }
 
-// Close closes and releases any resources retained by the QueryResult.
+// Close closes and releases any resources retained by the QueryResult, and finishes its span.
+// Queries run with `Execute` must be closed; the other methods close the query themselves.
 func (q *Query) Close() error {
+	err := q.close()
+	q.finishSpan(err)
+	return err
+}
+
+// close closes the rows, and the statement unless it belongs to the statement cache.
+func (q *Query) close() error {
 	var rowsErr error
 	var stmtErr error
 
@@ -40,7 +54,7 @@ func (q *Query) Close() error {
 		q.rows = nil
 	}
 
-	if !q.conn.useStatementCache {
+	if !q.conn.useStatementCache || q.tx != nil {
 		if q.stmt != nil {
 			stmtErr = q.stmt.Close()
 			q.stmt = nil
@@ -49,6 +63,12 @@ func (q *Query) Close() error {
 	return exception.Nest(rowsErr, stmtErr)
 }
 
+// WithStrict sets if `Out` and `Scan` should return a `NotFoundError` when there are no results.
+func (q *Query) WithStrict(flag bool) *Query {
+	q.strict = flag
+	return q
+}
+
 // CachedAs sets the statement cache label for the query.
 func (q *Query) CachedAs(cacheLabel string) *Query {
 	q.statementLabel = cacheLabel
@@ -56,19 +76,49 @@ func (q *Query) CachedAs(cacheLabel string) *Query {
 }
 
 // Execute runs a given query, yielding the raw results.
+// If a cached statement fails because its plan is stale after a schema change, or because the cache closed it while
+// it was in use, it is prepared again and retried once, unless the query is in a transaction.
+// The query should be closed with `Close` once the rows are read, which finishes its span.
 func (q *Query) Execute() (stmt *sql.Stmt, rows *sql.Rows, err error) {
+	q.details.args = q.args
+	q.details.cached = q.shouldCacheStatement()
+	q.startSpan()
+	stmt, rows, err = q.execute()
+	if err != nil && q.shouldRetry(err) {
+		stmt, rows, err = q.execute()
+	}
+	if err != nil {
+		err = exception.Wrap(err)
+		q.finishSpan(err)
+		return
+	}
+	q.stmt, q.rows = stmt, rows
+	q.scanStart = time.Now()
+	return
+}
+
+// execute prepares and runs the query once, returning unwrapped errors.
+func (q *Query) execute() (stmt *sql.Stmt, rows *sql.Rows, err error) {
 	var stmtErr error
+	prepareSpan, _ := q.conn.Tracer().StartSpan(q.spanCtx, SpanPrepare)
+	if len(q.statementLabel) > 0 {
+		prepareSpan.SetTag(TagLabel, q.statementLabel)
+	}
+	prepareStart := time.Now()
+	statement := q.conn.commentStatement(q.statement, q.statementLabel, q.ctx, q.shouldCacheStatement())
 	if q.shouldCacheStatement() {
-		stmt, stmtErr = q.conn.PrepareCached(q.statementLabel, q.statement, q.tx)
+		stmt, stmtErr = q.conn.PrepareCached(q.statementLabel, statement, q.tx)
 	} else {
-		stmt, stmtErr = q.conn.Prepare(q.statement, q.tx)
+		stmt, stmtErr = q.conn.Prepare(statement, q.tx)
 	}
+	q.details.prepareElapsed = q.details.prepareElapsed + time.Since(prepareStart)
+	finishSpan(prepareSpan, stmtErr)
 
 	if stmtErr != nil {
 		if q.shouldCacheStatement() {
 			q.conn.statementCache.InvalidateStatement(q.statementLabel)
 		}
-		err = exception.Wrap(stmtErr)
+		err = stmtErr
 		return
 	}
 
@@ -83,17 +133,23 @@ func (q *Query) Execute() (stmt *sql.Stmt, rows *sql.Rows, err error) {
 	}()
 
 	var queryErr error
+	executeStart := time.Now()
 	if q.ctx != nil {
 		rows, queryErr = stmt.QueryContext(q.ctx, q.args...)
 	} else {
 		rows, queryErr = stmt.Query(q.args...)
 	}
+	q.details.executeElapsed = q.details.executeElapsed + time.Since(executeStart)
 
 	if queryErr != nil {
-		if q.shouldCacheStatement() {
+		if q.shouldCacheStatement() && !isStatementClosed(queryErr) {
 			q.conn.statementCache.InvalidateStatement(q.statementLabel)
 		}
-		err = exception.Wrap(queryErr)
+		if q.shouldRetry(queryErr) {
+			// the statement is no longer cached and can't be used again.
+			stmt.Close()
+		}
+		err = queryErr
 	}
 	return
 }
@@ -117,6 +173,7 @@ func (q *Query) Any() (hasRows bool, err error) {
 	}
 
 	hasRows = q.rows.Next()
+	q.returned(hasRows)
 	return
 }
 
@@ -140,10 +197,12 @@ func (q *Query) None() (hasRows bool, err error) {
 	}
 
 	hasRows = !q.rows.Next()
+	q.returned(!hasRows)
 	return
 }
 
 // Scan writes the results to a given set of local variables.
+// In strict mode it returns a `NotFoundError` if there are no results.
 func (q *Query) Scan(args ...interface{}) (err error) {
 	defer func() { err = q.finalizer(recover(), err) }()
 
@@ -159,17 +218,22 @@ func (q *Query) Scan(args ...interface{}) (err error) {
 		return
 	}
 
-	if q.rows.Next() {
+	hasRow := q.rows.Next()
+	q.returned(hasRow)
+	if hasRow {
 		scanErr := q.rows.Scan(args...)
 		if scanErr != nil {
 			err = exception.Wrap(scanErr)
 		}
+	} else if q.strict {
+		err = &NotFoundError{Label: q.statementLabel}
 	}
 
 	return
 }
 
 // Out writes the query result to a single object via. reflection mapping.
+// In strict mode it returns a `NotFoundError` if there are no results, otherwise the object is left as is.
 func (q *Query) Out(object interface{}) (err error) {
 	defer func() { err = q.finalizer(recover(), err) }()
 
@@ -193,7 +257,9 @@ func (q *Query) Out(object interface{}) (err error) {
 
 	columnMeta := getCachedColumnCollectionFromInstance(object)
 	var popErr error
-	if q.rows.Next() {
+	hasRow := q.rows.Next()
+	q.returned(hasRow)
+	if hasRow {
 		if populatable, isPopulatable := object.(Populatable); isPopulatable {
 			popErr = populatable.Populate(q.rows)
 		} else {
@@ -203,6 +269,8 @@ func (q *Query) Out(object interface{}) (err error) {
 			err = popErr
 			return
 		}
+	} else if q.strict {
+		err = &NotFoundError{Label: q.statementLabel}
 	}
 
 	return
@@ -240,7 +308,9 @@ func (q *Query) OutMany(collection interface{}) (err error) {
 
 	var popErr error
 	didSetRows := false
+	q.details.addRowsReturned(0)
 	for q.rows.Next() {
+		q.details.addRowsReturned(1)
 		newObj := makeNew(sliceInnerType)
 
 		if isPopulatable {
@@ -279,7 +349,9 @@ func (q *Query) Each(consumer RowsConsumer) (err error) {
 		return
 	}
 
+	q.details.addRowsReturned(0)
 	for q.rows.Next() {
+		q.details.addRowsReturned(1)
 		err = consumer(q.rows)
 		if err != nil {
 			return err
@@ -298,16 +370,66 @@ func (q *Query) finalizer(r interface{}, err error) error {
 		err = exception.Nest(err, recoveryException)
 	}
 
-	if closeErr := q.Close(); closeErr != nil {
+	if !q.scanStart.IsZero() {
+		q.details.scanElapsed = time.Since(q.scanStart)
+	}
+
+	if closeErr := q.close(); closeErr != nil {
 		err = exception.Nest(err, closeErr)
 	}
 
+	q.finishSpan(err)
+	elapsed := time.Since(q.start)
+	q.conn.txStatement(q.tx)
+	q.conn.observeStatement(q.statementLabel, q.statement, elapsed, err)
+	q.conn.checkSlowQuery(q.statementLabel, q.statement, q.args, elapsed)
 	if q.fireEvents {
-		q.conn.fireEvent(FlagQuery, q.statement, time.Since(q.start), err, q.statementLabel)
+		q.conn.fireEvent(FlagQuery, q.statement, q.statementLabel, elapsed, err, q.details, q.ctx, q.tx)
 	}
 	return err
 }
 
+// startSpan starts the span for the query, unless it has already been started.
+// Queries in a tracked transaction are children of the transaction's span.
+// The span is finished when the query is closed, or if it fails to execute.
+func (q *Query) startSpan() {
+	if q.span != nil {
+		return
+	}
+	ctx := q.ctx
+	if txCtx := q.conn.txSpanContext(q.tx); txCtx != nil {
+		ctx = txCtx
+	} else if ctx == nil {
+		ctx = context.Background()
+	}
+	q.span, q.spanCtx = q.conn.Tracer().StartSpan(ctx, SpanQuery)
+	if len(q.statementLabel) > 0 {
+		q.span.SetTag(TagLabel, q.statementLabel)
+	}
+}
+
+// finishSpan finishes the span for the query, if it was started and isn't finished yet.
+func (q *Query) finishSpan(err error) {
+	if q.span != nil {
+		finishSpan(q.span, err)
+		q.span = nil
+	}
+}
+
+// returned records if a row was read from the results.
+func (q *Query) returned(hasRow bool) {
+	if hasRow {
+		q.details.addRowsReturned(1)
+	} else {
+		q.details.addRowsReturned(0)
+	}
+}
+
 func (q *Query) shouldCacheStatement() bool {
 	return q.conn.useStatementCache && len(q.statementLabel) > 0
 }
+
+// shouldRetry returns if a query that failed with a given error should be prepared again and retried.
+func (q *Query) shouldRetry(err error) bool {
+	return q.tx == nil && q.shouldCacheStatement() && (IsStalePlan(err) || isStatementClosed(err))
+}
@@ -0,0 +1,16 @@
+package spiffy
+
+import exception "github.com/blendlabs/go-exception"
+
+// rowsAffectedResult is a `sql.Result` for statements whose results were read with `RETURNING`.
+type rowsAffectedResult int64
+
+// LastInsertId implements sql.Result; it is not supported by postgres, use `RETURNING` instead.
+func (rar rowsAffectedResult) LastInsertId() (int64, error) {
+	return 0, exception.New("LastInsertId is not supported by this driver.")
+}
+
+// RowsAffected implements sql.Result.
+func (rar rowsAffectedResult) RowsAffected() (int64, error) {
+	return int64(rar), nil
+}
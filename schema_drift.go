@@ -0,0 +1,255 @@
+package spiffy
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// SchemaDriftKind is the kind of difference between a mapped type and its table.
+type SchemaDriftKind string
+
+const (
+	// SchemaDriftMissingTable means the mapped type's table doesn't exist.
+	SchemaDriftMissingTable SchemaDriftKind = "missing_table"
+	// SchemaDriftMissingColumn means a mapped column doesn't exist on the table.
+	SchemaDriftMissingColumn SchemaDriftKind = "missing_column"
+	// SchemaDriftExtraColumn means a column on the table isn't mapped.
+	SchemaDriftExtraColumn SchemaDriftKind = "extra_column"
+	// SchemaDriftTypeMismatch means a mapped column's field type can't hold the column's data type.
+	SchemaDriftTypeMismatch SchemaDriftKind = "type_mismatch"
+	// SchemaDriftNullabilityMismatch means a column is nullable and its field isn't, or the other way around.
+	SchemaDriftNullabilityMismatch SchemaDriftKind = "nullability_mismatch"
+)
+
+// SchemaDrift is a difference between a mapped type and its table.
+type SchemaDrift struct {
+	Kind   SchemaDriftKind
+	Table  string
+	Column string
+	// Expected is the data type or nullability of the mapped column, for mismatches.
+	Expected string
+	// Actual is the data type or nullability of the table's column, for mismatches.
+	Actual string
+}
+
+// String returns a description of the drift, ex: `users.email: type_mismatch (expected text, actual integer)`.
+func (sd SchemaDrift) String() string {
+	name := sd.Table
+	if len(sd.Column) > 0 {
+		name = fmt.Sprintf("%s.%s", sd.Table, sd.Column)
+	}
+	if len(sd.Expected) > 0 || len(sd.Actual) > 0 {
+		return fmt.Sprintf("%s: %s (expected %s, actual %s)", name, sd.Kind, sd.Expected, sd.Actual)
+	}
+	return fmt.Sprintf("%s: %s", name, sd.Kind)
+}
+
+// SchemaDriftError is returned by `VerifySchema` when mapped types don't match their tables.
+type SchemaDriftError struct {
+	Drift []SchemaDrift
+}
+
+// Error implements error.
+func (sde *SchemaDriftError) Error() string {
+	descriptions := make([]string, 0, len(sde.Drift))
+	for _, drift := range sde.Drift {
+		descriptions = append(descriptions, drift.String())
+	}
+	return fmt.Sprintf("schema drift: %s", strings.Join(descriptions, "; "))
+}
+
+// VerifySchema returns a `*SchemaDriftError` if any of the mapped types don't match their tables, ex: at startup.
+func (dbc *Connection) VerifySchema(objects ...DatabaseMapped) error {
+	return dbc.VerifySchemaInTx(nil, objects...)
+}
+
+// VerifySchemaInTx returns a `*SchemaDriftError` if any of the mapped types don't match their tables, within a transaction.
+func (dbc *Connection) VerifySchemaInTx(tx *sql.Tx, objects ...DatabaseMapped) error {
+	drift, err := dbc.CheckSchemaInTx(tx, objects...)
+	if err != nil {
+		return err
+	}
+	if len(drift) > 0 {
+		return &SchemaDriftError{Drift: drift}
+	}
+	return nil
+}
+
+// CheckSchema compares mapped types with their tables, and returns the differences; missing tables, missing or
+// extra columns, and columns whose data type or nullability doesn't match their field.
+func (dbc *Connection) CheckSchema(objects ...DatabaseMapped) ([]SchemaDrift, error) {
+	return dbc.CheckSchemaInTx(nil, objects...)
+}
+
+// CheckSchemaInTx compares mapped types with their tables within a transaction, and returns the differences.
+func (dbc *Connection) CheckSchemaInTx(tx *sql.Tx, objects ...DatabaseMapped) ([]SchemaDrift, error) {
+	var drift []SchemaDrift
+	introspector := dbc.IntrospectInTx("", tx)
+	for _, object := range objects {
+		tableName := TableName(object)
+		columns, err := introspector.Columns(tableName)
+		if err != nil {
+			return nil, err
+		}
+		drift = append(drift, compareSchema(tableName, getCachedColumnCollectionFromInstance(object), columns)...)
+	}
+	return drift, nil
+}
+
+// compareSchema returns the differences between a mapped type's columns and its table's columns.
+func compareSchema(tableName string, cols *ColumnCollection, columns []SchemaColumn) []SchemaDrift {
+	if len(columns) == 0 {
+		return []SchemaDrift{{Kind: SchemaDriftMissingTable, Table: tableName}}
+	}
+
+	var drift []SchemaDrift
+	lookup := map[string]SchemaColumn{}
+	for _, column := range columns {
+		lookup[column.Name] = column
+	}
+
+	for _, col := range cols.Columns() {
+		column, hasColumn := lookup[col.ColumnName]
+		if !hasColumn {
+			drift = append(drift, SchemaDrift{Kind: SchemaDriftMissingColumn, Table: tableName, Column: col.ColumnName})
+			continue
+		}
+		if !columnTypeMatches(col, column) {
+			expected, err := ColumnDataType(col)
+			if err != nil {
+				expected = col.FieldType.String()
+			}
+			drift = append(drift, SchemaDrift{Kind: SchemaDriftTypeMismatch, Table: tableName, Column: col.ColumnName, Expected: expected, Actual: column.DataType})
+		}
+		if isNullable := ColumnIsNullable(col) && !col.IsPrimaryKey; isNullable != column.IsNullable {
+			drift = append(drift, SchemaDrift{Kind: SchemaDriftNullabilityMismatch, Table: tableName, Column: col.ColumnName, Expected: nullability(isNullable), Actual: nullability(column.IsNullable)})
+		}
+	}
+
+	for _, column := range columns {
+		if !cols.HasColumn(column.Name) {
+			drift = append(drift, SchemaDrift{Kind: SchemaDriftExtraColumn, Table: tableName, Column: column.Name})
+		}
+	}
+	return drift
+}
+
+func nullability(isNullable bool) string {
+	if isNullable {
+		return "null"
+	}
+	return "not null"
+}
+
+// dataTypeAliases maps postgres data type aliases to the names `information_schema.columns` uses.
+var dataTypeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bool":        "boolean",
+	"float4":      "real",
+	"float8":      "double precision",
+	"decimal":     "numeric",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+}
+
+var dataTypeModifiers = regexp.MustCompile(`\s*\(.*\)`)
+
+// normalizeDataType lower cases a data type and removes its modifiers, ex: `VARCHAR(255)` becomes `character varying`.
+func normalizeDataType(dataType string) string {
+	normalized := strings.ToLower(strings.TrimSpace(dataTypeModifiers.ReplaceAllString(dataType, "")))
+	if alias, hasAlias := dataTypeAliases[normalized]; hasAlias {
+		return alias
+	}
+	return normalized
+}
+
+// columnTypeMatches returns if a column's data type matches its `db_type` tag, or can be scanned into its field.
+func columnTypeMatches(col Column, column SchemaColumn) bool {
+	if len(col.DataType) > 0 {
+		expected := normalizeDataType(col.DataType)
+		if strings.HasSuffix(expected, "[]") {
+			return column.DataType == "ARRAY"
+		}
+		return expected == column.DataType || expected == column.UDTName
+	}
+
+	if col.IsJSON {
+		switch column.DataType {
+		case "json", "jsonb", "text", "character varying":
+			return true
+		}
+		return false
+	}
+
+	t := col.FieldType
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case ddlTimeType, ddlNullTimeType:
+		return strings.HasPrefix(column.DataType, "timestamp") || column.DataType == "date"
+	case ddlUUIDType:
+		return column.DataType == "uuid" || column.DataType == "bytea"
+	case ddlNullStringType:
+		return isTextDataType(column)
+	case ddlNullInt64Type, ddlNullInt32Type:
+		return isIntegerDataType(column.DataType)
+	case ddlNullFloat64Type:
+		return isIntegerDataType(column.DataType) || isFloatDataType(column.DataType)
+	case ddlNullBoolType:
+		return column.DataType == "boolean"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return column.DataType == "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return isIntegerDataType(column.DataType) || column.DataType == "numeric"
+	case reflect.Float32, reflect.Float64:
+		return isIntegerDataType(column.DataType) || isFloatDataType(column.DataType)
+	case reflect.String:
+		return isTextDataType(column)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return column.DataType == "bytea" || isTextDataType(column)
+		}
+		return column.DataType == "ARRAY"
+	}
+	// types that implement `sql.Scanner` can scan any data type.
+	return reflect.PtrTo(t).Implements(scannerType)
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func isIntegerDataType(dataType string) bool {
+	return dataType == "smallint" || dataType == "integer" || dataType == "bigint"
+}
+
+func isFloatDataType(dataType string) bool {
+	return dataType == "real" || dataType == "double precision" || dataType == "numeric"
+}
+
+// isTextDataType returns if a column can be scanned into a string; text types, enums, uuids and the like.
+func isTextDataType(column SchemaColumn) bool {
+	switch column.DataType {
+	case "text", "character varying", "character", "uuid", "USER-DEFINED", "name", "inet", "cidr", "xml", "json", "jsonb":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package spiffy
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type driftObj struct {
+	ID         int               `db:"id,pk,serial"`
+	Name       string            `db:"name"`
+	Count      int               `db:"count"`
+	Note       *string           `db:"note"`
+	Status     string            `db:"status" db_type:"varchar(32)"`
+	Labels     map[string]string `db:"labels,json"`
+	CreatedUTC time.Time         `db:"created_utc"`
+	Renamed    string            `db:"renamed"`
+}
+
+func (do driftObj) TableName() string {
+	return "drift_obj"
+}
+
+func TestCompareSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	cols := getCachedColumnCollectionFromInstance(driftObj{})
+	assert.Equal([]SchemaDrift{{Kind: SchemaDriftMissingTable, Table: "drift_obj"}}, compareSchema("drift_obj", cols, nil))
+
+	drift := compareSchema("drift_obj", cols, []SchemaColumn{
+		{Name: "id", DataType: "integer", UDTName: "int4"},
+		{Name: "name", DataType: "text", UDTName: "text", IsNullable: true},
+		{Name: "count", DataType: "text", UDTName: "text"},
+		{Name: "note", DataType: "character varying", UDTName: "varchar", IsNullable: true},
+		{Name: "status", DataType: "character varying", UDTName: "varchar"},
+		{Name: "labels", DataType: "jsonb", UDTName: "jsonb"},
+		{Name: "created_utc", DataType: "timestamp without time zone", UDTName: "timestamp"},
+		{Name: "original", DataType: "text", UDTName: "text"},
+	})
+	assert.Equal([]SchemaDrift{
+		{Kind: SchemaDriftNullabilityMismatch, Table: "drift_obj", Column: "name", Expected: "not null", Actual: "null"},
+		{Kind: SchemaDriftTypeMismatch, Table: "drift_obj", Column: "count", Expected: "bigint", Actual: "text"},
+		{Kind: SchemaDriftMissingColumn, Table: "drift_obj", Column: "renamed"},
+		{Kind: SchemaDriftExtraColumn, Table: "drift_obj", Column: "original"},
+	}, drift)
+	assert.Equal("drift_obj.count: type_mismatch (expected bigint, actual text)", drift[1].String())
+}
+
+func TestNormalizeDataType(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("character varying", normalizeDataType("VARCHAR(255)"))
+	assert.Equal("numeric", normalizeDataType("numeric(20, 4)"))
+	assert.Equal("timestamp with time zone", normalizeDataType("timestamptz"))
+	assert.Equal("citext", normalizeDataType("citext"))
+}
+
+func TestConnectionVerifySchema(t *testing.T) {
+	assert := assert.New(t)
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	err = Default().VerifySchemaInTx(tx, driftObj{})
+	assert.True(err != nil && err.(*SchemaDriftError).Drift[0].Kind == SchemaDriftMissingTable)
+
+	statements, err := CreateTableDDL(driftObj{})
+	assert.Nil(err)
+	for _, statement := range statements {
+		assert.Nil(Default().ExecInTx(statement, tx))
+	}
+	assert.Nil(Default().VerifySchemaInTx(tx, driftObj{}))
+
+	assert.Nil(Default().ExecInTx("ALTER TABLE drift_obj RENAME COLUMN renamed TO original", tx))
+	drift, err := Default().CheckSchemaInTx(tx, driftObj{})
+	assert.Nil(err)
+	assert.Equal([]SchemaDrift{
+		{Kind: SchemaDriftMissingColumn, Table: "drift_obj", Column: "renamed"},
+		{Kind: SchemaDriftExtraColumn, Table: "drift_obj", Column: "original"},
+	}, drift)
+}
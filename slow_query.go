@@ -0,0 +1,105 @@
+package spiffy
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultExplainTimeout is the default timeout for capturing the explain output of a slow query.
+	DefaultExplainTimeout = 5 * time.Second
+
+	// maxSlowQueryExplains is the most slow queries that are explained at once.
+	maxSlowQueryExplains = 2
+)
+
+// explainableStatements are the leading keywords of statements that can be explained.
+var explainableStatements = []string{"select", "insert", "update", "delete", "with", "values"}
+
+// WithSlowQueryThreshold sets the elapsed time above which labeled statements trigger a `db.slow_query` event,
+// and returns a reference to the connection. A threshold of zero or less disables slow query events.
+func (dbc *Connection) WithSlowQueryThreshold(threshold time.Duration) *Connection {
+	dbc.slowQueryThreshold = threshold
+	return dbc
+}
+
+// SlowQueryThreshold returns the slow query threshold.
+func (dbc *Connection) SlowQueryThreshold() time.Duration {
+	return dbc.slowQueryThreshold
+}
+
+// WithSlowQueryExplain sets the fraction of slow query events, from 0 to 1, that capture the output of `EXPLAIN (FORMAT JSON)`
+// for the statement and its arguments, and returns a reference to the connection.
+// The explain runs in the background on a pooled connection outside of any transaction, and the event is triggered when it finishes.
+// At most a couple of explains run at once, so a slow database isn't given more work in proportion to its slowness;
+// sampled events over that limit are triggered without explain output.
+func (dbc *Connection) WithSlowQueryExplain(sampleRate float64) *Connection {
+	dbc.slowQueryExplainRate = sampleRate
+	return dbc
+}
+
+// SlowQueryExplain returns the fraction of slow query events that capture explain output.
+func (dbc *Connection) SlowQueryExplain() float64 {
+	return dbc.slowQueryExplainRate
+}
+
+// checkSlowQuery triggers a slow query event if a labeled statement took longer than the slow query threshold.
+func (dbc *Connection) checkSlowQuery(label, statement string, args []interface{}, elapsed time.Duration) {
+	if dbc.log == nil || dbc.slowQueryThreshold <= 0 || len(label) == 0 || elapsed <= dbc.slowQueryThreshold {
+		return
+	}
+
+	event := NewSlowQueryEvent(label, statement, elapsed, dbc.slowQueryThreshold)
+	if dbc.slowQueryExplainRate <= 0 || !isExplainable(statement) || rand.Float64() >= dbc.slowQueryExplainRate || !dbc.startExplain() {
+		dbc.log.Trigger(event)
+		return
+	}
+
+	go func() {
+		defer atomic.AddInt32(&dbc.slowQueryExplains, -1)
+		event.explain, event.explainErr = dbc.explain(statement, args...)
+		dbc.log.Trigger(event)
+	}()
+}
+
+// startExplain reserves one of the `maxSlowQueryExplains` explains that can run at once, and returns if it did.
+func (dbc *Connection) startExplain() bool {
+	if atomic.AddInt32(&dbc.slowQueryExplains, 1) > maxSlowQueryExplains {
+		atomic.AddInt32(&dbc.slowQueryExplains, -1)
+		return false
+	}
+	return true
+}
+
+// explain returns the output of `EXPLAIN (FORMAT JSON)` for a statement and its arguments.
+// The statement is planned but not run.
+func (dbc *Connection) explain(statement string, args ...interface{}) (string, error) {
+	db, err := dbc.Open()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultExplainTimeout)
+	defer cancel()
+
+	var plan string
+	err = db.Connection.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+statement, args...).Scan(&plan)
+	return plan, err
+}
+
+// isExplainable returns if a statement can be explained.
+func isExplainable(statement string) bool {
+	fields := strings.Fields(strings.TrimLeft(strings.TrimSpace(statement), "("))
+	if len(fields) == 0 {
+		return false
+	}
+	keyword := strings.ToLower(fields[0])
+	for _, explainable := range explainableStatements {
+		if keyword == explainable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package spiffy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestIsExplainable(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isExplainable("select 1"))
+	assert.True(isExplainable("  SELECT 1"))
+	assert.True(isExplainable("(select 1) union (select 2)"))
+	assert.True(isExplainable("WITH x AS (select 1) select * from x"))
+	assert.True(isExplainable("UPDATE bench_object SET name = $1"))
+	assert.False(isExplainable("CREATE TABLE foo (id int)"))
+	assert.False(isExplainable("TRUNCATE bench_object"))
+	assert.False(isExplainable(""))
+}
+
+func TestConnectionExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	plan, err := Default().explain("select $1::int as value", 1)
+	assert.Nil(err)
+	assert.True(strings.Contains(plan, `"Plan"`))
+}
+
+func TestConnectionStartExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := New()
+	for index := 0; index < maxSlowQueryExplains; index++ {
+		assert.True(conn.startExplain())
+	}
+	assert.False(conn.startExplain())
+	assert.Equal(int32(maxSlowQueryExplains), conn.slowQueryExplains)
+}
+
+func TestSlowQueryEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	e := NewSlowQueryEvent("test_label", "select 1", 2*time.Second, time.Second)
+	assert.Equal(FlagSlowQuery, e.Flag())
+	e.explain = `[{"Plan": {}}]`
+
+	buf := bytes.NewBuffer(nil)
+	e.WriteText(nil, buf)
+	assert.Equal("(2s > 1s) test_label\nselect 1\n[{\"Plan\": {}}]", buf.String())
+
+	obj := e.WriteJSON()
+	assert.Equal("select 1", obj["queryBody"])
+	assert.Equal(`[{"Plan": {}}]`, obj["explain"])
+}
@@ -0,0 +1,41 @@
+package spiffytest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/blendlabs/spiffy"
+)
+
+var (
+	defaultHarness *Harness
+	defaultLock    = sync.Mutex{}
+)
+
+// Default returns a harness on `spiffy.Default()`, creating it on first use.
+func Default() *Harness {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	if defaultHarness == nil {
+		defaultHarness = New(spiffy.Default())
+	}
+	return defaultHarness
+}
+
+// SetDefault sets the default harness, ex: to one with migrations.
+func SetDefault(harness *Harness) {
+	defaultLock.Lock()
+	defaultHarness = harness
+	defaultLock.Unlock()
+}
+
+// DB returns a database context from the default harness that is rolled back when the test finishes.
+//
+//	func TestGetUser(t *testing.T) {
+//		db := spiffytest.DB(t)
+//		...
+//	}
+func DB(t testing.TB) *spiffy.DB {
+	t.Helper()
+	return Default().DB(t)
+}
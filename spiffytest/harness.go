@@ -0,0 +1,212 @@
+// Package spiffytest hands tests database contexts bound to transactions that are rolled back when the test finishes,
+// so tests can write to a real database without cleaning up after themselves.
+package spiffytest
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	exception "github.com/blendlabs/go-exception"
+	"github.com/blendlabs/spiffy"
+	"github.com/blendlabs/spiffy/migration"
+	"github.com/lib/pq"
+)
+
+// New returns a new harness that runs tests in transactions on a connection.
+func New(conn *spiffy.Connection) *Harness {
+	return &Harness{
+		conn:   conn,
+		lock:   &sync.Mutex{},
+		scopes: map[string]*scope{},
+	}
+}
+
+// Harness hands tests database contexts bound to transactions that are rolled back when the test finishes.
+//
+// A test's first call to `DB` begins a new transaction. Subtests of a test that has a transaction get a savepoint
+// in it instead, which is rolled back when the subtest finishes; subtests of the same test, including parallel
+// subtests, take turns on the transaction, because postgres savepoints are a stack.
+type Harness struct {
+	conn       *spiffy.Connection
+	migrations *migration.Group
+	schema     string
+
+	setupOnce *sync.Once
+	setupErr  error
+	scratch   *spiffy.Connection
+
+	lock       *sync.Mutex
+	scopes     map[string]*scope
+	savepoints int64
+}
+
+// scope is the transaction a test runs in, and the lock its subtests take turns on.
+type scope struct {
+	tx   *sql.Tx
+	lock *sync.Mutex
+}
+
+// WithMigrations sets a migration group that is applied, once, to a scratch schema that the tests run in,
+// and returns a reference to the harness. Call `Close` after the tests run to drop the schema.
+func (h *Harness) WithMigrations(group *migration.Group) *Harness {
+	h.migrations = group
+	h.setupOnce = &sync.Once{}
+	return h
+}
+
+// WithSchema sets the name of the scratch schema, and returns a reference to the harness.
+// It defaults to `spiffytest_` followed by a random suffix. The name is quoted when the schema is created and dropped,
+// so it should be lower case to match the connection's search path.
+func (h *Harness) WithSchema(schema string) *Harness {
+	h.schema = schema
+	return h
+}
+
+// Schema returns the name of the scratch schema, if migrations have been applied.
+func (h *Harness) Schema() string {
+	return h.schema
+}
+
+// Conn returns the connection tests run on; the scratch schema's connection if the harness has migrations.
+func (h *Harness) Conn() (*spiffy.Connection, error) {
+	if h.migrations == nil {
+		return h.conn, nil
+	}
+	h.setupOnce.Do(func() {
+		h.scratch, h.setupErr = h.setup()
+	})
+	return h.scratch, h.setupErr
+}
+
+// DB returns a database context bound to a transaction that is rolled back when the test finishes.
+// If the test is a subtest of a test that has a transaction, the context is bound to a savepoint in it.
+// Calling it again in the same test returns a context bound to the same transaction.
+func (h *Harness) DB(t testing.TB) *spiffy.DB {
+	t.Helper()
+
+	conn, err := h.Conn()
+	if err != nil {
+		t.Fatalf("spiffytest: %+v", err)
+	}
+	if current := h.currentScope(t.Name()); current != nil {
+		return conn.DB(current.tx)
+	}
+	if parent := h.parentScope(t.Name()); parent != nil {
+		return h.savepoint(t, conn, parent)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatalf("spiffytest: %+v", err)
+	}
+	h.register(t.Name(), &scope{tx: tx, lock: &sync.Mutex{}})
+	t.Cleanup(func() {
+		h.unregister(t.Name())
+		if err := conn.Rollback(tx); err != nil && err != sql.ErrTxDone {
+			t.Errorf("spiffytest: %+v", exception.Wrap(err))
+		}
+	})
+	return conn.DB(tx)
+}
+
+// Close drops the scratch schema and closes its connection, if migrations were applied.
+func (h *Harness) Close() error {
+	if h.scratch == nil {
+		return nil
+	}
+	err := h.scratch.Close()
+	dropErr := h.conn.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", pq.QuoteIdentifier(h.schema)))
+	if dropErr != nil {
+		return exception.Nest(err, dropErr)
+	}
+	return exception.Wrap(err)
+}
+
+// savepoint waits for the parent test's other subtests to finish with its transaction,
+// then binds a context to a new savepoint in it.
+func (h *Harness) savepoint(t testing.TB, conn *spiffy.Connection, parent *scope) *spiffy.DB {
+	t.Helper()
+
+	parent.lock.Lock()
+	name := fmt.Sprintf("spiffytest_%d", atomic.AddInt64(&h.savepoints, 1))
+	if err := conn.ExecInTx(fmt.Sprintf("SAVEPOINT %s", name), parent.tx); err != nil {
+		parent.lock.Unlock()
+		t.Fatalf("spiffytest: %+v", err)
+	}
+
+	h.register(t.Name(), &scope{tx: parent.tx, lock: &sync.Mutex{}})
+	t.Cleanup(func() {
+		defer parent.lock.Unlock()
+		h.unregister(t.Name())
+		if err := conn.ExecInTx(fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", name), parent.tx); err != nil {
+			t.Errorf("spiffytest: %+v", err)
+			return
+		}
+		if err := conn.ExecInTx(fmt.Sprintf("RELEASE SAVEPOINT %s", name), parent.tx); err != nil {
+			t.Errorf("spiffytest: %+v", err)
+		}
+	})
+	return conn.DB(parent.tx)
+}
+
+// setup creates the scratch schema, opens a connection whose search path is set to it, and applies the migrations.
+// The migrations' guards check the scratch schema only, so same-named tables elsewhere on the search path don't skip steps.
+func (h *Harness) setup() (*spiffy.Connection, error) {
+	if len(h.schema) == 0 {
+		h.schema = fmt.Sprintf("spiffytest_%s", spiffy.UUIDv4().ToShortString())
+	}
+	err := h.conn.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pq.QuoteIdentifier(h.schema)))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := spiffy.Config{}
+	if h.conn.Config != nil {
+		cfg = *h.conn.Config
+	}
+	scratch, err := spiffy.NewFromConfig(cfg.WithSchema(h.schema)).Open()
+	if err != nil {
+		return nil, err
+	}
+	err = h.migrations.Apply(scratch)
+	if err != nil {
+		return scratch, err
+	}
+	return scratch, nil
+}
+
+// currentScope returns the scope of a test, if it has a transaction already.
+func (h *Harness) currentScope(name string) *scope {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.scopes[name]
+}
+
+// parentScope returns the scope of the closest parent test that has a transaction, if any.
+func (h *Harness) parentScope(name string) *scope {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for index := strings.LastIndex(name, "/"); index > 0; index = strings.LastIndex(name, "/") {
+		name = name[:index]
+		if parent, hasParent := h.scopes[name]; hasParent {
+			return parent
+		}
+	}
+	return nil
+}
+
+func (h *Harness) register(name string, s *scope) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.scopes[name] = s
+}
+
+func (h *Harness) unregister(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.scopes, name)
+}
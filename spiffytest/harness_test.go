@@ -0,0 +1,129 @@
+package spiffytest
+
+import (
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/spiffy"
+	"github.com/blendlabs/spiffy/migration"
+)
+
+type harnessObj struct {
+	ID   int    `db:"id,pk,serial"`
+	Name string `db:"name"`
+}
+
+func (ho harnessObj) TableName() string {
+	return "spiffytest_harness_obj"
+}
+
+func createHarnessTable(db *spiffy.DB) error {
+	return db.Exec("CREATE TABLE IF NOT EXISTS spiffytest_harness_obj (id serial not null primary key, name varchar(255))")
+}
+
+func countHarnessObjs(db *spiffy.DB) (count int, err error) {
+	err = db.Query("SELECT count(*) FROM spiffytest_harness_obj").Scan(&count)
+	return
+}
+
+func TestHarnessRollsBack(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("writes", func(t *testing.T) {
+		db := DB(t)
+		assert.NotNil(db.Tx())
+		assert.Equal(db.Tx(), DB(t).Tx())
+		assert.Nil(createHarnessTable(db))
+		assert.Nil(db.Create(&harnessObj{Name: "foo"}))
+	})
+
+	assert.Empty(Default().scopes)
+	exists, err := spiffy.Default().Query("SELECT 1 FROM pg_tables WHERE tablename = $1", harnessObj{}.TableName()).Any()
+	assert.Nil(err)
+	assert.False(exists)
+}
+
+func TestHarnessSavepoints(t *testing.T) {
+	assert := assert.New(t)
+
+	db := DB(t)
+	assert.Nil(createHarnessTable(db))
+	assert.Nil(db.Create(&harnessObj{Name: "parent"}))
+
+	t.Run("group", func(t *testing.T) {
+		for index := 0; index < 3; index++ {
+			name := fmt.Sprintf("child_%d", index)
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				testHarnessChild(t, db, name)
+			})
+		}
+	})
+
+	count, err := countHarnessObjs(db)
+	assert.Nil(err)
+	assert.Equal(1, count)
+}
+
+func testHarnessChild(t *testing.T, parent *spiffy.DB, name string) {
+	assert := assert.New(t)
+
+	child := DB(t)
+	assert.Equal(parent.Tx(), child.Tx())
+	assert.Nil(child.Create(&harnessObj{Name: name}))
+	// a second call in the same test returns the same savepoint rather than waiting on it.
+	assert.Equal(child.Tx(), DB(t).Tx())
+
+	count, err := countHarnessObjs(child)
+	assert.Nil(err)
+	assert.Equal(2, count)
+}
+
+func TestHarnessMigrations(t *testing.T) {
+	assert := assert.New(t)
+
+	harness := New(spiffy.Default()).WithMigrations(migration.New(
+		migration.NewStep(
+			migration.TableNotExists(harnessObj{}.TableName()),
+			migration.Statements("CREATE TABLE spiffytest_harness_obj (id serial not null primary key, name varchar(255))"),
+		),
+	))
+	// cleanups run last in first out, so the schema is dropped after the test's transaction is rolled back.
+	t.Cleanup(func() { assert.Nil(harness.Close()) })
+
+	db := harness.DB(t)
+	assert.NotEmpty(harness.Schema())
+	assert.Nil(db.Create(&harnessObj{Name: "foo"}))
+
+	var schema string
+	assert.Nil(db.Query("SELECT table_schema FROM information_schema.tables WHERE table_name = $1", harnessObj{}.TableName()).Scan(&schema))
+	assert.Equal(harness.Schema(), schema)
+}
+
+func TestHarnessMigrationsShadowedTable(t *testing.T) {
+	assert := assert.New(t)
+
+	// a same-named table outside the scratch schema must not satisfy the migrations' guards.
+	assert.Nil(spiffy.Default().Exec("CREATE TABLE public.spiffytest_harness_obj (id serial not null primary key)"))
+	t.Cleanup(func() { assert.Nil(spiffy.Default().Exec("DROP TABLE IF EXISTS public.spiffytest_harness_obj")) })
+
+	harness := New(spiffy.Default()).WithMigrations(migration.New(
+		migration.NewStep(
+			migration.TableNotExists(harnessObj{}.TableName()),
+			migration.Statements("CREATE TABLE spiffytest_harness_obj (id serial not null primary key, name varchar(255))"),
+		),
+	))
+	t.Cleanup(func() { assert.Nil(harness.Close()) })
+
+	db := harness.DB(t)
+	assert.Nil(db.Create(&harnessObj{Name: "foo"}))
+
+	exists, err := db.Query("SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2", harness.Schema(), harnessObj{}.TableName()).Any()
+	assert.Nil(err)
+	assert.True(exists)
+
+	count, err := countHarnessObjs(db)
+	assert.Nil(err)
+	assert.Equal(1, count)
+}
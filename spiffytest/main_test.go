@@ -0,0 +1,18 @@
+package spiffytest
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/blendlabs/spiffy"
+)
+
+// TestMain is the testing entrypoint.
+func TestMain(m *testing.M) {
+	err := spiffy.OpenDefault(spiffy.NewFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(m.Run())
+}
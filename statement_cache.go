@@ -1,8 +1,16 @@
 package spiffy
 
 import (
+	"container/list"
 	"database/sql"
+	"regexp"
 	"sync"
+	"time"
+)
+
+const (
+	// maxBackgroundPrepares is the most statements that are prepared in the background at once.
+	maxBackgroundPrepares = 4
 )
 
 // newStatementCache returns a new `StatementCache`.
@@ -10,15 +18,115 @@ func newStatementCache(dbc *sql.DB) *StatementCache {
 	return &StatementCache{
 		dbc:       dbc,
 		cacheLock: &sync.Mutex{},
-		cache:     make(map[string]*sql.Stmt),
+		cache:     make(map[string]*list.Element),
+		lru:       list.New(),
+		pending:   make(map[string]bool),
+	}
+}
+
+// CachedStatement is a prepared statement and the label it is cached by.
+type CachedStatement struct {
+	Label     string
+	Statement string
+	Stmt      *sql.Stmt
+	LastUsed  time.Time
+}
+
+// StatementCacheStats are the counters for a statement cache.
+type StatementCacheStats struct {
+	// Size is the number of statements in the cache.
+	Size int
+	// MaxSize is the maximum number of statements in the cache; if it's zero or less the cache is unbounded.
+	MaxSize int
+	// Hits is the number of times a statement was found in the cache.
+	Hits int64
+	// Misses is the number of times a statement was not found in the cache and had to be prepared.
+	Misses int64
+	// Evictions is the number of statements that were removed to make room for new statements.
+	Evictions int64
+	// PrepareElapsed is the total time spent preparing statements on cache misses.
+	PrepareElapsed time.Duration
+	// BackgroundPrepareErrors is the number of statements that failed to prepare in the background.
+	BackgroundPrepareErrors int64
+}
+
+// AveragePrepareElapsed returns the average time spent preparing a statement on a cache miss.
+func (scs StatementCacheStats) AveragePrepareElapsed() time.Duration {
+	if scs.Misses == 0 {
+		return 0
 	}
+	return scs.PrepareElapsed / time.Duration(scs.Misses)
 }
 
 // StatementCache is a cache of prepared statements.
+// If it has a maximum size, the least recently used statements are evicted and closed when it's full.
+// Statements that are closed while another goroutine holds them fail with `sql: statement is closed`,
+// which the connection handles by preparing the statement again and retrying once.
 type StatementCache struct {
 	dbc       *sql.DB
 	cacheLock *sync.Mutex
-	cache     map[string]*sql.Stmt
+	cache     map[string]*list.Element
+	lru       *list.List
+	maxSize   int
+	pending   map[string]bool
+
+	hits                    int64
+	misses                  int64
+	evictions               int64
+	prepareElapsed          time.Duration
+	backgroundPrepareErrors int64
+}
+
+// WithMaxSize sets the maximum number of statements to cache and returns a reference to the cache.
+// A max size of zero or less means the cache is unbounded.
+func (sc *StatementCache) WithMaxSize(maxSize int) *StatementCache {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+	sc.maxSize = maxSize
+	sc.evict()
+	return sc
+}
+
+// MaxSize returns the maximum number of statements to cache.
+func (sc *StatementCache) MaxSize() int {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+	return sc.maxSize
+}
+
+// Len returns the number of cached statements.
+func (sc *StatementCache) Len() int {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+	return sc.lru.Len()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (sc *StatementCache) Stats() StatementCacheStats {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+	return StatementCacheStats{
+		Size:                    sc.lru.Len(),
+		MaxSize:                 sc.maxSize,
+		Hits:                    sc.hits,
+		Misses:                  sc.misses,
+		Evictions:               sc.evictions,
+		PrepareElapsed:          sc.prepareElapsed,
+		BackgroundPrepareErrors: sc.backgroundPrepareErrors,
+	}
+}
+
+// Statements returns the cached statements, most recently used first.
+// It is intended for debugging; the statements should not be closed.
+func (sc *StatementCache) Statements() []CachedStatement {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+
+	statements := make([]CachedStatement, 0, sc.lru.Len())
+	for element := sc.lru.Front(); element != nil; element = element.Next() {
+		statements = append(statements, *element.Value.(*CachedStatement))
+	}
+	return statements
 }
 
 // Close implements io.Closer.
@@ -28,8 +136,8 @@ func (sc *StatementCache) Close() error {
 
 func (sc *StatementCache) closeAll() error {
 	var err error
-	for _, stmt := range sc.cache {
-		err = stmt.Close()
+	for element := sc.lru.Front(); element != nil; element = element.Next() {
+		err = element.Value.(*CachedStatement).Stmt.Close()
 		if err != nil {
 			return err
 		}
@@ -43,53 +151,141 @@ func (sc *StatementCache) Clear() error {
 	defer sc.cacheLock.Unlock()
 
 	err := sc.closeAll()
-	sc.cache = make(map[string]*sql.Stmt)
+	sc.cache = make(map[string]*list.Element)
+	sc.lru.Init()
 	return err
 }
 
 // HasStatement returns if the cache contains a statement.
 func (sc *StatementCache) HasStatement(statementID string) bool {
-	return sc.getCachedStatement(statementID) != nil
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+	_, hasStatement := sc.cache[statementID]
+	return hasStatement
 }
 
-// InvalidateStatement removes a statement from the cache.
+// InvalidateStatement removes and closes a cached statement.
 func (sc *StatementCache) InvalidateStatement(statementID string) {
 	sc.cacheLock.Lock()
 	defer sc.cacheLock.Unlock()
 
-	if _, hasStatement := sc.cache[statementID]; hasStatement {
+	if element, hasStatement := sc.cache[statementID]; hasStatement {
+		sc.lru.Remove(element)
 		delete(sc.cache, statementID)
+		element.Value.(*CachedStatement).Stmt.Close()
 	}
 }
 
-func (sc *StatementCache) getCachedStatement(statementID string) *sql.Stmt {
+// InvalidateTable removes and closes the cached statements that reference a table, and returns how many were removed.
+// It should be called after DDL changes a table, so that statements are prepared again with fresh plans.
+func (sc *StatementCache) InvalidateTable(tableName string) int {
+	references := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(tableName) + `\b`)
+
 	sc.cacheLock.Lock()
 	defer sc.cacheLock.Unlock()
-	if stmt, hasStmt := sc.cache[statementID]; hasStmt {
-		return stmt
-	}
 
-	return nil
+	var removed int
+	for element := sc.lru.Front(); element != nil; {
+		next := element.Next()
+		cached := element.Value.(*CachedStatement)
+		if references.MatchString(cached.Statement) {
+			sc.lru.Remove(element)
+			delete(sc.cache, cached.Label)
+			cached.Stmt.Close()
+			removed = removed + 1
+		}
+		element = next
+	}
+	return removed
 }
 
 // Prepare returns a cached expression for a statement, or creates and caches a new one.
+// The cache is not locked while the statement is prepared, so a slow prepare does not block other lookups.
 func (sc *StatementCache) Prepare(id, statementProvider string) (*sql.Stmt, error) {
-	cached := sc.getCachedStatement(id)
-	if cached != nil {
+	if cached := sc.get(id); cached != nil {
 		return cached, nil
 	}
 
+	start := time.Now()
+	stmt, err := sc.dbc.Prepare(statementProvider)
+	elapsed := time.Since(start)
+
 	sc.cacheLock.Lock()
 	defer sc.cacheLock.Unlock()
-	if stmt, hasStmt := sc.cache[id]; hasStmt {
-		return stmt, nil
-	}
 
-	stmt, err := sc.dbc.Prepare(statementProvider)
+	sc.misses = sc.misses + 1
+	sc.prepareElapsed = sc.prepareElapsed + elapsed
 	if err != nil {
 		return nil, err
 	}
 
-	sc.cache[id] = stmt
+	// another caller may have cached the statement while we were preparing it.
+	if element, hasStmt := sc.cache[id]; hasStmt {
+		stmt.Close()
+		return element.Value.(*CachedStatement).Stmt, nil
+	}
+
+	sc.cache[id] = sc.lru.PushFront(&CachedStatement{
+		Label:     id,
+		Statement: statementProvider,
+		Stmt:      stmt,
+		LastUsed:  time.Now().UTC(),
+	})
+	sc.evict()
 	return stmt, nil
 }
+
+// prepareInBackground prepares and caches a statement on another goroutine, and returns if it started.
+// A statement is only prepared once at a time, and at most `maxBackgroundPrepares` statements are prepared at once,
+// so a burst of transactions can't take every pooled connection; failures are counted in the stats.
+func (sc *StatementCache) prepareInBackground(id, statement string) bool {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+
+	if _, hasStmt := sc.cache[id]; hasStmt || sc.pending[id] || len(sc.pending) >= maxBackgroundPrepares {
+		return false
+	}
+	sc.pending[id] = true
+
+	go func() {
+		_, err := sc.Prepare(id, statement)
+
+		sc.cacheLock.Lock()
+		defer sc.cacheLock.Unlock()
+		delete(sc.pending, id)
+		if err != nil {
+			sc.backgroundPrepareErrors = sc.backgroundPrepareErrors + 1
+		}
+	}()
+	return true
+}
+
+// get returns a cached statement and marks it as recently used, or nil if it isn't cached.
+func (sc *StatementCache) get(id string) *sql.Stmt {
+	sc.cacheLock.Lock()
+	defer sc.cacheLock.Unlock()
+
+	if element, hasStmt := sc.cache[id]; hasStmt {
+		sc.hits = sc.hits + 1
+		sc.lru.MoveToFront(element)
+		cached := element.Value.(*CachedStatement)
+		cached.LastUsed = time.Now().UTC()
+		return cached.Stmt
+	}
+	return nil
+}
+
+// evict removes and closes the least recently used statements until the cache is within its max size.
+func (sc *StatementCache) evict() {
+	if sc.maxSize <= 0 {
+		return
+	}
+	for sc.lru.Len() > sc.maxSize {
+		element := sc.lru.Back()
+		cached := element.Value.(*CachedStatement)
+		sc.lru.Remove(element)
+		delete(sc.cache, cached.Label)
+		cached.Stmt.Close()
+		sc.evictions = sc.evictions + 1
+	}
+}
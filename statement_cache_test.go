@@ -1,7 +1,9 @@
 package spiffy
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	assert "github.com/blendlabs/go-assert"
 )
@@ -22,3 +24,133 @@ func TestStatementCachePrepare(t *testing.T) {
 	assert.NotNil(stmt)
 	assert.True(sc.HasStatement(query))
 }
+
+func TestStatementCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newStatementCache(Default().Connection).WithMaxSize(2)
+	defer sc.Close()
+
+	_, err := sc.Prepare("one", "select 1")
+	assert.Nil(err)
+	_, err = sc.Prepare("two", "select 2")
+	assert.Nil(err)
+
+	// use `one` so `two` is the least recently used.
+	_, err = sc.Prepare("one", "select 1")
+	assert.Nil(err)
+
+	_, err = sc.Prepare("three", "select 3")
+	assert.Nil(err)
+
+	assert.Equal(2, sc.Len())
+	assert.True(sc.HasStatement("one"))
+	assert.False(sc.HasStatement("two"))
+	assert.True(sc.HasStatement("three"))
+
+	stats := sc.Stats()
+	assert.Equal(2, stats.Size)
+	assert.Equal(2, stats.MaxSize)
+	assert.Equal(int64(1), stats.Hits)
+	assert.Equal(int64(3), stats.Misses)
+	assert.Equal(int64(1), stats.Evictions)
+	assert.NotZero(stats.PrepareElapsed)
+
+	statements := sc.Statements()
+	assert.Len(statements, 2)
+	assert.Equal("three", statements[0].Label)
+	assert.Equal("select 3", statements[0].Statement)
+	assert.Equal("one", statements[1].Label)
+}
+
+func TestStatementCacheUnbounded(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newStatementCache(Default().Connection).WithMaxSize(-1)
+	defer sc.Close()
+
+	for x := 0; x < 10; x++ {
+		_, err := sc.Prepare(fmt.Sprintf("statement_%d", x), fmt.Sprintf("select %d", x))
+		assert.Nil(err)
+	}
+	assert.Equal(10, sc.Len())
+	assert.Zero(sc.Stats().Evictions)
+}
+
+func TestStatementCacheInvalidateTable(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newStatementCache(Default().Connection)
+	defer sc.Close()
+
+	_, err := sc.Prepare("bench_get", "SELECT id FROM bench_object WHERE id = $1")
+	assert.Nil(err)
+	_, err = sc.Prepare("bench_other", "SELECT 'bench_object_other'")
+	assert.Nil(err)
+	_, err = sc.Prepare("ok", "SELECT 'ok'")
+	assert.Nil(err)
+
+	assert.Equal(1, sc.InvalidateTable("BENCH_OBJECT"))
+	assert.False(sc.HasStatement("bench_get"))
+	assert.True(sc.HasStatement("bench_other"))
+	assert.True(sc.HasStatement("ok"))
+}
+
+func TestStatementCacheInvalidateStatement(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newStatementCache(Default().Connection)
+	defer sc.Close()
+
+	stmt, err := sc.Prepare("ok", "SELECT 'ok'")
+	assert.Nil(err)
+
+	sc.InvalidateStatement("ok")
+	assert.False(sc.HasStatement("ok"))
+
+	// callers still holding the statement see it closed, and retry with a fresh one.
+	_, err = stmt.Exec()
+	assert.True(isStatementClosed(err))
+	assert.False(isStatementClosed(nil))
+}
+
+// waitForBackgroundPrepares waits until a statement cache has no statements being prepared in the background.
+func waitForBackgroundPrepares(sc *StatementCache) {
+	for {
+		sc.cacheLock.Lock()
+		pending := len(sc.pending)
+		sc.cacheLock.Unlock()
+		if pending == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStatementCachePrepareInBackground(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newStatementCache(Default().Connection)
+	defer sc.Close()
+
+	assert.True(sc.prepareInBackground("ok", "SELECT 'ok'"))
+	// the same statement isn't prepared twice at once.
+	assert.False(sc.prepareInBackground("ok", "SELECT 'ok'"))
+
+	var started int
+	for x := 0; x < maxBackgroundPrepares*2; x++ {
+		if sc.prepareInBackground(fmt.Sprintf("statement_%d", x), fmt.Sprintf("select %d", x)) {
+			started = started + 1
+		}
+	}
+	assert.True(started < maxBackgroundPrepares*2)
+
+	waitForBackgroundPrepares(sc)
+	assert.True(sc.HasStatement("ok"))
+	assert.False(sc.prepareInBackground("ok", "SELECT 'ok'"), "cached statements aren't prepared again")
+
+	assert.True(sc.prepareInBackground("bad", "SELECT FROM WHERE"))
+	waitForBackgroundPrepares(sc)
+	assert.Equal(int64(1), sc.Stats().BackgroundPrepareErrors)
+	assert.False(sc.HasStatement("bad"))
+}
@@ -0,0 +1,197 @@
+package spiffy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// SpanPrepare is the operation name of spans around preparing statements.
+	SpanPrepare = "db.prepare"
+	// SpanExec is the operation name of spans around statements that don't return rows.
+	SpanExec = "db.exec"
+	// SpanQuery is the operation name of spans around statements that return rows; they finish when the rows are closed.
+	SpanQuery = "db.query"
+	// SpanTx is the operation name of spans around transactions; they finish when the transaction is committed or rolled back.
+	SpanTx = "db.tx"
+
+	// TagLabel is the span tag for the statement label.
+	TagLabel = "db.label"
+	// TagTable is the span tag for the table name of a mapped object.
+	TagTable = "db.table"
+	// TagErrorClass is the span tag for the class of an error, ex: `integrity_constraint_violation`.
+	TagErrorClass = "error.class"
+	// TagTxResult is the span tag for how a transaction ended, either `db.tx.commit`, `db.tx.rollback` or `TxResultUnknown`.
+	TagTxResult = "db.tx.result"
+
+	// TxResultUnknown is the `db.tx.result` tag of transactions that were ended directly on the `*sql.Tx`,
+	// rather than through the connection.
+	TxResultUnknown = "unknown"
+)
+
+// Tracer starts spans around database calls.
+type Tracer interface {
+	// StartSpan starts a span as a child of any span in the context, and returns the span and a context that carries it.
+	StartSpan(ctx context.Context, operation string) (Span, context.Context)
+}
+
+// Span is a timed operation started by a `Tracer`.
+type Span interface {
+	// SetTag sets a tag on the span.
+	SetTag(key, value string)
+	// Finish finishes the span with the error of the operation, if any.
+	Finish(err error)
+}
+
+// ErrorClass returns a short class for an error for tagging spans.
+// Postgres errors are classed by their error code class name, ex: `integrity_constraint_violation`.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class().Name()
+	}
+	switch {
+	case IsNotFound(err):
+		return "not_found"
+	case errors.Is(err, sql.ErrNoRows):
+		return "no_rows"
+	case errors.Is(err, sql.ErrTxDone):
+		return "tx_done"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	}
+	return "error"
+}
+
+// finishSpan tags a span with the class of an error, if any, and finishes it.
+func finishSpan(span Span, err error) {
+	if err != nil {
+		span.SetTag(TagErrorClass, ErrorClass(err))
+	}
+	span.Finish(err)
+}
+
+// --------------------------------------------------------------------------------
+// Noop Tracer
+// --------------------------------------------------------------------------------
+
+// NoopTracer is a tracer that doesn't record anything; it is the default tracer for connections.
+type NoopTracer struct{}
+
+// StartSpan implements `Tracer`.
+func (nt NoopTracer) StartSpan(ctx context.Context, operation string) (Span, context.Context) {
+	return noopSpan{}, ctx
+}
+
+type noopSpan struct{}
+
+func (ns noopSpan) SetTag(key, value string) {}
+func (ns noopSpan) Finish(err error)         {}
+
+// --------------------------------------------------------------------------------
+// Recording Tracer
+// --------------------------------------------------------------------------------
+
+type recordedSpanKey struct{}
+
+// NewRecordingTracer returns a new tracer that records spans in memory, for tests.
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{lock: &sync.Mutex{}}
+}
+
+// RecordingTracer is a tracer that records spans in memory.
+type RecordingTracer struct {
+	lock  *sync.Mutex
+	spans []*RecordedSpan
+}
+
+// StartSpan implements `Tracer`.
+func (rt *RecordingTracer) StartSpan(ctx context.Context, operation string) (Span, context.Context) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	span := &RecordedSpan{
+		tracer:    rt,
+		ID:        len(rt.spans) + 1,
+		Operation: operation,
+		Tags:      map[string]string{},
+		Started:   time.Now().UTC(),
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if parent, hasParent := ctx.Value(recordedSpanKey{}).(*RecordedSpan); hasParent {
+		span.ParentID = parent.ID
+	}
+	rt.spans = append(rt.spans, span)
+	return span, context.WithValue(ctx, recordedSpanKey{}, span)
+}
+
+// Spans returns copies of the recorded spans in the order they were started.
+func (rt *RecordingTracer) Spans() []RecordedSpan {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	spans := make([]RecordedSpan, 0, len(rt.spans))
+	for _, span := range rt.spans {
+		copied := *span
+		copied.Tags = make(map[string]string, len(span.Tags))
+		for key, value := range span.Tags {
+			copied.Tags[key] = value
+		}
+		spans = append(spans, copied)
+	}
+	return spans
+}
+
+// Reset clears the recorded spans.
+func (rt *RecordingTracer) Reset() {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	rt.spans = nil
+}
+
+// RecordedSpan is a span recorded by a `RecordingTracer`.
+type RecordedSpan struct {
+	tracer *RecordingTracer
+
+	// ID is the order the span was started in, starting at 1.
+	ID int
+	// ParentID is the ID of the span's parent, or 0 if it has none.
+	ParentID  int
+	Operation string
+	Tags      map[string]string
+	Err       error
+	Started   time.Time
+	Finished  time.Time
+}
+
+// IsFinished returns if the span was finished.
+func (rs *RecordedSpan) IsFinished() bool {
+	return !rs.Finished.IsZero()
+}
+
+// SetTag implements `Span`.
+func (rs *RecordedSpan) SetTag(key, value string) {
+	rs.tracer.lock.Lock()
+	defer rs.tracer.lock.Unlock()
+	rs.Tags[key] = value
+}
+
+// Finish implements `Span`.
+func (rs *RecordedSpan) Finish(err error) {
+	rs.tracer.lock.Lock()
+	defer rs.tracer.lock.Unlock()
+	rs.Err = err
+	rs.Finished = time.Now().UTC()
+}
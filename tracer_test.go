@@ -0,0 +1,115 @@
+package spiffy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestErrorClass(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Empty(ErrorClass(nil))
+	assert.Equal("not_found", ErrorClass(&NotFoundError{}))
+	assert.Equal("no_rows", ErrorClass(fmt.Errorf("outer: %w", sql.ErrNoRows)))
+	assert.Equal("canceled", ErrorClass(context.Canceled))
+	assert.Equal("error", ErrorClass(fmt.Errorf("test")))
+}
+
+func TestRecordingTracer(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer := NewRecordingTracer()
+	parent, ctx := tracer.StartSpan(context.Background(), SpanQuery)
+	child, _ := tracer.StartSpan(ctx, SpanPrepare)
+	child.SetTag(TagLabel, "test")
+	child.Finish(nil)
+
+	spans := tracer.Spans()
+	assert.Len(spans, 2)
+	assert.Zero(spans[0].ParentID)
+	assert.False(spans[0].IsFinished())
+	assert.Equal(spans[0].ID, spans[1].ParentID)
+	assert.Equal("test", spans[1].Tags[TagLabel])
+	assert.True(spans[1].IsFinished())
+
+	parent.Finish(fmt.Errorf("test"))
+	assert.NotNil(tracer.Spans()[0].Err)
+
+	tracer.Reset()
+	assert.Empty(tracer.Spans())
+}
+
+func TestConnectionTracer(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := NewFromEnv()
+	defer conn.Close()
+	tracer := NewRecordingTracer()
+	conn.WithTracer(tracer)
+
+	tx, err := conn.Begin()
+	assert.Nil(err)
+	defer conn.Rollback(tx)
+
+	err = createTable(tx)
+	assert.Nil(err)
+	tracer.Reset()
+
+	var objs []benchObj
+	err = conn.GetAllInTx(&objs, tx)
+	assert.Nil(err)
+
+	spans := tracer.Spans()
+	assert.Len(spans, 2)
+	assert.Equal(SpanQuery, spans[0].Operation)
+	assert.Equal("bench_object", spans[0].Tags[TagTable])
+	assert.Equal("bench_object_get_all", spans[0].Tags[TagLabel])
+	assert.True(spans[0].IsFinished())
+	assert.Equal(SpanPrepare, spans[1].Operation)
+	assert.Equal(spans[0].ID, spans[1].ParentID)
+
+	tracer.Reset()
+	err = conn.ExecInTx("select * from bench_object_does_not_exist", tx)
+	assert.NotNil(err)
+	spans = tracer.Spans()
+	assert.NotEmpty(spans)
+	assert.NotEmpty(spans[0].Tags[TagErrorClass])
+}
+
+func TestConnectionTracerBeginContext(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := NewFromEnv()
+	defer conn.Close()
+	tracer := NewRecordingTracer()
+	conn.WithTracer(tracer)
+
+	request, ctx := tracer.StartSpan(context.Background(), "request")
+	defer request.Finish(nil)
+
+	tx, err := conn.BeginContext(ctx)
+	assert.Nil(err)
+	assert.Nil(conn.ExecInTx("select 1", tx))
+
+	_, _, err = conn.QueryInTx("select * from bench_object_does_not_exist", tx).Execute()
+	assert.NotNil(err)
+	assert.Nil(conn.Rollback(tx))
+
+	spans := tracer.Spans()
+	// request, tx, exec, its prepare, query, its prepare.
+	assert.Len(spans, 6)
+	assert.Equal(SpanTx, spans[1].Operation)
+	assert.Equal(spans[0].ID, spans[1].ParentID)
+	assert.Equal(SpanExec, spans[2].Operation)
+	assert.Equal(spans[1].ID, spans[2].ParentID)
+	assert.Equal(SpanQuery, spans[4].Operation)
+	assert.Equal(spans[1].ID, spans[4].ParentID)
+	assert.True(spans[4].IsFinished(), "queries that fail to execute finish their span")
+	assert.NotNil(spans[4].Err)
+	assert.True(spans[1].IsFinished())
+	assert.Equal(string(FlagTxRollback), spans[1].Tags[TagTxResult])
+}
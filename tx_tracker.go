@@ -0,0 +1,157 @@
+package spiffy
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"sync"
+	"time"
+
+	logger "github.com/blendlabs/go-logger"
+)
+
+// newTxTracker returns a new `txTracker`.
+func newTxTracker() *txTracker {
+	return &txTracker{
+		lock:  &sync.Mutex{},
+		state: make(map[string]*txState),
+	}
+}
+
+// txState is what is tracked about an open transaction.
+type txState struct {
+	started    time.Time
+	statements int64
+	span       Span
+	spanCtx    context.Context
+}
+
+// txTracker tracks the transactions begun by a connection until they are committed or rolled back, for transaction
+// lifecycle events.
+// Transactions are tracked by id so the tracker doesn't keep them alive; transactions whose end isn't observed,
+// because the connection's pool wasn't opened with a `Connector`, stop being tracked, and have their spans finished,
+// when they are garbage collected.
+type txTracker struct {
+	lock  *sync.Mutex
+	state map[string]*txState
+}
+
+// begin starts tracking a transaction, its span and the context that carries the span.
+func (tt *txTracker) begin(tx *sql.Tx, span Span, spanCtx context.Context) {
+	id := TxID(tx)
+	tt.lock.Lock()
+	tt.state[id] = &txState{started: time.Now(), span: span, spanCtx: spanCtx}
+	tt.lock.Unlock()
+
+	runtime.SetFinalizer(tx, func(*sql.Tx) { tt.abandoned(id) })
+}
+
+// statement counts a statement executed in a transaction, if it is tracked.
+func (tt *txTracker) statement(tx *sql.Tx) {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	if state, hasState := tt.state[TxID(tx)]; hasState {
+		state.statements = state.statements + 1
+	}
+}
+
+// spanContext returns the context that carries a transaction's span, or nil if it isn't tracked.
+func (tt *txTracker) spanContext(tx *sql.Tx) context.Context {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	if state, hasState := tt.state[TxID(tx)]; hasState {
+		return state.spanCtx
+	}
+	return nil
+}
+
+// end stops tracking a transaction, returning its state if it was tracked.
+func (tt *txTracker) end(tx *sql.Tx) (*txState, bool) {
+	runtime.SetFinalizer(tx, nil)
+	return tt.remove(TxID(tx))
+}
+
+// abandoned stops tracking a transaction that was garbage collected without being ended through the connection,
+// and finishes its span.
+func (tt *txTracker) abandoned(id string) {
+	if state, hasState := tt.remove(id); hasState {
+		state.span.SetTag(TagTxResult, TxResultUnknown)
+		state.span.Finish(nil)
+	}
+}
+
+// remove stops tracking a transaction by id, returning its state if it was tracked.
+func (tt *txTracker) remove(id string) (*txState, bool) {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	state, hasState := tt.state[id]
+	if hasState {
+		delete(tt.state, id)
+	}
+	return state, hasState
+}
+
+// len returns the number of tracked transactions.
+func (tt *txTracker) len() int {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	return len(tt.state)
+}
+
+// txHookContextKey is the context key for the `txHook` of a transaction being begun.
+type txHookContextKey struct{}
+
+// withTxHook returns a context carrying a hook that observes the end of the transaction begun with it.
+func withTxHook(ctx context.Context) (context.Context, *txHook) {
+	hook := &txHook{lock: &sync.Mutex{}}
+	return context.WithValue(ctx, txHookContextKey{}, hook), hook
+}
+
+// getTxHook returns the hook carried by a context, if any.
+func getTxHook(ctx context.Context) *txHook {
+	if ctx == nil {
+		return nil
+	}
+	hook, _ := ctx.Value(txHookContextKey{}).(*txHook)
+	return hook
+}
+
+// txHook observes a transaction ending on its driver connection, however it's ended: through the connection,
+// directly on the `*sql.Tx`, or by the transaction's context being canceled.
+// The driver transaction can end before the `*sql.Tx` is returned to be tracked, so an end is held until it's observed.
+type txHook struct {
+	lock     *sync.Mutex
+	observer func(flag logger.Flag, err error)
+	hasEnded bool
+	flag     logger.Flag
+	err      error
+}
+
+// observe sets the function called when the transaction ends, calling it right away if it already has.
+func (th *txHook) observe(observer func(flag logger.Flag, err error)) {
+	th.lock.Lock()
+	if th.hasEnded {
+		th.lock.Unlock()
+		observer(th.flag, th.err)
+		return
+	}
+	th.observer = observer
+	th.lock.Unlock()
+}
+
+// ended is called by the driver transaction when it's committed or rolled back.
+func (th *txHook) ended(flag logger.Flag, err error) {
+	th.lock.Lock()
+	if th.hasEnded {
+		th.lock.Unlock()
+		return
+	}
+	th.hasEnded = true
+	th.flag, th.err = flag, err
+	observer := th.observer
+	th.lock.Unlock()
+
+	if observer != nil {
+		observer(flag, err)
+	}
+}
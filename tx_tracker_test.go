@@ -0,0 +1,85 @@
+package spiffy
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+)
+
+func TestTxTracker(t *testing.T) {
+	assert := assert.New(t)
+
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	tt := newTxTracker()
+	tt.statement(tx)
+	assert.Zero(tt.len())
+
+	tt.begin(tx, noopSpan{}, context.Background())
+	tt.statement(tx)
+	tt.statement(tx)
+	assert.Equal(1, tt.len())
+
+	state, tracked := tt.end(tx)
+	assert.True(tracked)
+	assert.Equal(int64(2), state.statements)
+	assert.Zero(tt.len())
+
+	_, tracked = tt.end(tx)
+	assert.False(tracked)
+}
+
+func TestTxTrackerAbandoned(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer := NewRecordingTracer()
+	tt := newTxTracker()
+
+	tx, err := Default().Begin()
+	assert.Nil(err)
+	span, spanCtx := tracer.StartSpan(context.Background(), SpanTx)
+	tt.begin(tx, span, spanCtx)
+	assert.Equal(1, tt.len())
+
+	// ending the transaction directly leaves it tracked until it's garbage collected.
+	assert.Nil(tx.Rollback())
+	tx = nil
+	for attempt := 0; attempt < 100 && tt.len() > 0; attempt++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Zero(tt.len())
+
+	spans := tracer.Spans()
+	assert.Len(spans, 1)
+	assert.True(spans[0].IsFinished())
+	assert.Equal(TxResultUnknown, spans[0].Tags[TagTxResult])
+}
+
+func TestTxHook(t *testing.T) {
+	assert := assert.New(t)
+
+	// an end before the observer is set is held until it's observed.
+	ctx, hook := withTxHook(context.Background())
+	assert.True(hook == getTxHook(ctx))
+	hook.ended(FlagTxRollback, nil)
+	hook.ended(FlagTxCommit, nil)
+
+	var flags []logger.Flag
+	hook.observe(func(flag logger.Flag, err error) { flags = append(flags, flag) })
+	assert.Equal([]logger.Flag{FlagTxRollback}, flags)
+
+	_, hook = withTxHook(context.Background())
+	hook.observe(func(flag logger.Flag, err error) { flags = append(flags, flag) })
+	hook.ended(FlagTxCommit, nil)
+	hook.ended(FlagTxRollback, nil)
+	assert.Equal([]logger.Flag{FlagTxRollback, FlagTxCommit}, flags)
+
+	assert.Nil(getTxHook(context.Background()))
+}
@@ -29,6 +29,14 @@ func Tx(txs ...*sql.Tx) *sql.Tx {
 	return OptionalTx(txs...)
 }
 
+// TxID returns an identifier for a transaction that is unique among open transactions, for use in events and logs.
+func TxID(tx *sql.Tx) string {
+	if tx == nil {
+		return ""
+	}
+	return fmt.Sprintf("%p", tx)
+}
+
 // TableNameByType returns the table name for a given reflect.Type by instantiating it and calling o.TableName().
 // The type must implement DatabaseMapped or an exception will be returned.
 func TableNameByType(t reflect.Type) string {
@@ -151,6 +159,14 @@ func paramTokensCSV(num int) string {
 	return str
 }
 
+// batchSize returns the number of rows that fit in a single statement for a given number of parameters per row.
+func batchSize(parametersPerRow int) int {
+	if parametersPerRow < 1 {
+		return maxStatementParameters
+	}
+	return maxStatementParameters / parametersPerRow
+}
+
 // makeNewDatabaseMapped returns a new instance of a database mapped type.
 func makeNewDatabaseMapped(t reflect.Type) (DatabaseMapped, error) {
 	newInterface := reflect.New(t).Interface()
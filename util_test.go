@@ -71,3 +71,11 @@ func TestTableName(t *testing.T) {
 	assert.Equal("not_simple_type_with_name", TableName(SimpleTypeWithName{}))
 	assert.Equal("not_simple_type_with_name", TableName(&SimpleTypeWithName{}))
 }
+
+func TestBatchSize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(maxStatementParameters, batchSize(0))
+	assert.Equal(maxStatementParameters, batchSize(1))
+	assert.Equal(maxStatementParameters/3, batchSize(3))
+}